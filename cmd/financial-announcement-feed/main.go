@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/chamzzzzzz/financial/source/cninfo"
+)
+
+var (
+	market   string
+	code     string
+	keyword  string
+	category string
+	plate    string
+	trade    string
+	interval time.Duration
+	maxPages int
+	markFile string
+	format   string
+	forward  string
+	timeout  time.Duration
+)
+
+type event struct {
+	Time         string               `json:"time"`
+	Code         string               `json:"code"`
+	Name         string               `json:"name"`
+	ID           string               `json:"id"`
+	Title        string               `json:"title"`
+	URL          string               `json:"url"`
+	Announcement *cninfo.Announcement `json:"announcement"`
+}
+
+func main() {
+	flag.StringVar(&market, "market", "a-share", "market: a-share, hk, fund, bond")
+	flag.StringVar(&code, "code", "", "stock code, pinyin or name, empty to poll the whole market")
+	flag.StringVar(&keyword, "keyword", "", "title keyword")
+	flag.StringVar(&category, "category", "", "announcement category. eg: annual, semi-annual, q1, q3")
+	flag.StringVar(&plate, "plate", "", "boards separated by comma: "+plateNames())
+	flag.StringVar(&trade, "trade", "", "CSRC industry codes separated by comma. eg: C,J")
+	flag.DurationVar(&interval, "interval", cninfo.DefaultPollInterval, "poll interval")
+	flag.IntVar(&maxPages, "max-pages", cninfo.DefaultPollPages, "max pages read per poll to catch up")
+	flag.StringVar(&markFile, "mark", "poll.json", "file keeping the latest announcement seen across restarts, empty to start from now every time")
+	flag.StringVar(&format, "format", "text", "output format: text, json")
+	flag.StringVar(&forward, "forward", "", "url to POST every announcement to as json, empty to disable")
+	flag.DurationVar(&timeout, "timeout", 30*time.Second, "cninfo request timeout")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	source := &cninfo.Source{Timeout: timeout}
+	q, err := query(ctx, source)
+	if err != nil {
+		log.Printf("build query fail. err='%s'", err)
+		os.Exit(1)
+	}
+	poller := &cninfo.Poller{
+		Source:   source,
+		Query:    q,
+		Interval: interval,
+		MaxPages: maxPages,
+		File:     markFile,
+	}
+	client := &http.Client{Timeout: timeout}
+	enc := json.NewEncoder(os.Stdout)
+	for e := range poller.Run(ctx) {
+		if e.Err != nil {
+			log.Printf("poll announcements error. err='%v'", e.Err)
+			continue
+		}
+		announcement := e.Announcement
+		v := &event{
+			Time:         announcement.Time().Format("2006-01-02 15:04:05"),
+			Code:         announcement.SecCode,
+			Name:         announcement.SecName,
+			ID:           announcement.AnnouncementID,
+			Title:        announcement.AnnouncementTitle,
+			URL:          source.AdjunctURL(announcement),
+			Announcement: announcement,
+		}
+		switch format {
+		case "json":
+			err = enc.Encode(v)
+		default:
+			_, err = fmt.Printf("%s %s %s %s %s\n", v.Time, v.Code, v.Name, v.Title, v.URL)
+		}
+		if err != nil {
+			log.Printf("write announcement fail. err='%s'", err)
+			os.Exit(1)
+		}
+		if forward != "" {
+			if err := post(ctx, client, v); err != nil {
+				log.Printf("forward announcement error. id=%s, err='%v'", v.ID, err)
+			}
+		}
+	}
+}
+
+func query(ctx context.Context, source *cninfo.Source) (*cninfo.SearchQuery, error) {
+	m, err := cninfo.ParseMarket(market)
+	if err != nil {
+		return nil, err
+	}
+	q := &cninfo.SearchQuery{
+		Market:  m,
+		Keyword: keyword,
+	}
+	if q.Plates, err = cninfo.ParsePlates(plate); err != nil {
+		return nil, err
+	}
+	if q.Trades, err = cninfo.ParseTrades(trade); err != nil {
+		return nil, err
+	}
+	if category != "" {
+		c, err := cninfo.ParseCategory(category)
+		if err != nil {
+			return nil, err
+		}
+		q.Category = c
+	}
+	if code != "" {
+		stocks, err := source.GetMarketStockListContext(ctx, m)
+		if err != nil {
+			return nil, err
+		}
+		if q.Stock, err = cninfo.NewStockIndex(stocks).Resolve(code); err != nil {
+			return nil, err
+		}
+	}
+	return q, q.Validate()
+}
+
+func plateNames() string {
+	var names []string
+	for _, plate := range cninfo.Plates {
+		names = append(names, plate.Name())
+	}
+	return strings.Join(names, ", ")
+}
+
+func post(ctx context.Context, client *http.Client, v *event) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", forward, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("status code:%d", resp.StatusCode)
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"flag"
 	"fmt"
 	"log"
@@ -9,35 +10,57 @@ import (
 	"net"
 	"net/smtp"
 	"os"
+	"os/signal"
 	"strings"
 	"text/template"
 	"time"
 
+	"github.com/chamzzzzzz/financial/source"
 	"github.com/chamzzzzzz/financial/source/cninfo"
+	_ "github.com/chamzzzzzz/financial/source/sse"
+	_ "github.com/chamzzzzzz/financial/source/szse"
 )
 
 var (
-	codes   = os.Getenv("FINANCIAL_DIVIDEND_MONITOR_CODES")
-	addr    = os.Getenv("FINANCIAL_DIVIDEND_MONITOR_SMTP_ADDR")
-	user    = os.Getenv("FINANCIAL_DIVIDEND_MONITOR_SMTP_USER")
-	pass    = os.Getenv("FINANCIAL_DIVIDEND_MONITOR_SMTP_PASS")
-	to      = os.Getenv("FINANCIAL_DIVIDEND_MONITOR_SMTP_TO")
-	source  = "From: {{.From}}\r\nTo: {{.To}}\r\nSubject: {{.Subject}}\r\nContent-Type: {{.ContentType}}\r\n\r\n{{.Body}}"
-	tpl     *template.Template
-	stocks  []*cninfo.Stock
-	monitor bool
+	codes    = os.Getenv("FINANCIAL_DIVIDEND_MONITOR_CODES")
+	addr     = os.Getenv("FINANCIAL_DIVIDEND_MONITOR_SMTP_ADDR")
+	user     = os.Getenv("FINANCIAL_DIVIDEND_MONITOR_SMTP_USER")
+	pass     = os.Getenv("FINANCIAL_DIVIDEND_MONITOR_SMTP_PASS")
+	to       = os.Getenv("FINANCIAL_DIVIDEND_MONITOR_SMTP_TO")
+	message  = "From: {{.From}}\r\nTo: {{.To}}\r\nSubject: {{.Subject}}\r\nContent-Type: {{.ContentType}}\r\n\r\n{{.Body}}"
+	tpl      *template.Template
+	stocks   []*cninfo.Stock
+	monitor  bool
+	timeout  time.Duration
+	cacheDir string
+	provider source.Provider
+
+	parsePlan bool
 )
 
 func main() {
 	flag.BoolVar(&monitor, "monitor", false, "monitor")
-	flag.StringVar(&codes, "codes", codes, "stock code list to monitor or update, separated by comma. eg: 000001,000002")
+	flag.StringVar(&codes, "codes", codes, "stock list to monitor or update by code, pinyin or name, separated by comma. eg: 000001,pfyh,万科A")
 	flag.StringVar(&addr, "addr", addr, "notification smtp addr")
 	flag.StringVar(&user, "user", user, "notification smtp user")
 	flag.StringVar(&pass, "pass", pass, "notification smtp pass")
 	flag.StringVar(&to, "to", to, "notification smtp to")
+	flag.DurationVar(&timeout, "timeout", 30*time.Second, "cninfo request timeout")
+	flag.StringVar(&cacheDir, "cache-dir", "", "cninfo response cache dir, empty to disable")
+	providerName := flag.String("provider", "cninfo", "providers to fail over between, separated by comma: "+strings.Join(source.Providers(), ", "))
+	flag.BoolVar(&parsePlan, "parse-plan", false, "append parsed dividend plan per share (cash, cash after tax, bonus, transfer, currency) to dividend files")
 	flag.Parse()
 
-	s, err := (&cninfo.Source{}).GetStockList()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var err error
+	provider, err = source.OpenFailover(*providerName, &source.Config{Timeout: timeout, CacheDir: cacheDir}, nil)
+	if err != nil {
+		log.Printf("open provider fail. err='%s'", err)
+		return
+	}
+	s, err := provider.GetStockListContext(ctx)
 	if err != nil {
 		log.Printf("get stock list fail. err='%s'", err)
 		return
@@ -49,10 +72,7 @@ func main() {
 			return
 		}
 	}
-	m := make(map[string]*cninfo.Stock)
-	for _, stock := range s {
-		m[stock.Code] = stock
-	}
+	index := cninfo.NewStockIndex(s)
 
 	c := strings.Split(codes, ",")
 	if b, err := os.ReadFile("codes.txt"); err == nil {
@@ -64,23 +84,24 @@ func main() {
 
 	d := make(map[string]struct{})
 	for _, code := range c {
+		code = strings.TrimSpace(code)
 		if code == "" {
 			continue
 		}
-		if _, ok := d[code]; ok {
-			continue
-		}
-		stock := m[code]
-		if stock == nil {
-			log.Printf("stock not found. code=%s", code)
+		stock, err := index.Resolve(code)
+		if err != nil {
+			log.Printf("resolve stock fail. err='%s'", err)
 			return
 		}
+		if _, ok := d[stock.Code]; ok {
+			continue
+		}
 		stocks = append(stocks, stock)
-		d[code] = struct{}{}
+		d[stock.Code] = struct{}{}
 		if monitor {
-			log.Printf("monitoring stock. code=%s", code)
+			log.Printf("monitoring stock. code=%s, name=%s", stock.Code, stock.Zwjc)
 		} else {
-			log.Printf("updating stock. code=%s", code)
+			log.Printf("updating stock. code=%s, name=%s", stock.Code, stock.Zwjc)
 		}
 	}
 
@@ -96,27 +117,35 @@ func main() {
 	funcs := template.FuncMap{
 		"bencoding": mime.BEncoding.Encode,
 	}
-	tpl = template.Must(template.New("mail").Funcs(funcs).Parse(source))
+	tpl = template.Must(template.New("mail").Funcs(funcs).Parse(message))
 
 	for {
-		check()
+		check(ctx)
 		if !monitor {
 			break
 		}
-		now := time.Now()
+		now := cninfo.Now()
 		next := time.Date(now.Year(), now.Month(), now.Day()+1, 10, 0, 0, 0, now.Location())
 		log.Printf("next check at %s\n", next.Format("2006-01-02 15:04:05"))
-		time.Sleep(next.Sub(now))
+		select {
+		case <-time.After(next.Sub(now)):
+		case <-ctx.Done():
+			log.Printf("monitor stopped. err='%v'", ctx.Err())
+			return
+		}
 	}
 }
 
-func check() {
+func check(ctx context.Context) {
 	log.Printf("check start at %s", time.Now().Format("2006-01-02 15:04:05"))
 	t := time.Now()
 
-	source := &cninfo.Source{}
 	for _, stock := range stocks {
-		records, err := source.GetDividendRecords(stock)
+		if ctx.Err() != nil {
+			log.Printf("check interrupted. err='%v'", ctx.Err())
+			break
+		}
+		records, err := provider.GetDividendRecordsContext(ctx, stock)
 		if err != nil {
 			log.Printf("check fail, get dividend records error. code=%s, err='%v'", stock.Code, err)
 			continue
@@ -149,7 +178,12 @@ func notification(stock *cninfo.Stock, records []*cninfo.DividendRecord) {
 		return
 	}
 	latest := records[0]
-	if latest.PayDate != time.Now().Format("2006-01-02") {
+	payDate, err := latest.PayTime()
+	if err != nil {
+		log.Printf("send notification skip. err='%s'", err)
+		return
+	}
+	if payDate.IsZero() || !payDate.Equal(cninfo.Today()) {
 		return
 	}
 
@@ -216,7 +250,11 @@ func writeStockDividendRecords(stock *cninfo.Stock, records []*cninfo.DividendRe
 		if record.PayDate == "" {
 			payDate = "--"
 		}
-		if _, err := buf.WriteString(fmt.Sprintf("%s,%s,%s,%s,%s\n", period, record.Plan, record.RecordDate, record.ExDividendDate, payDate)); err != nil {
+		line := fmt.Sprintf("%s,%s,%s,%s,%s", period, record.Plan, record.RecordDate, record.ExDividendDate, payDate)
+		if parsePlan {
+			line += "," + planFields(stock, record)
+		}
+		if _, err := buf.WriteString(line + "\n"); err != nil {
 			log.Printf("write stock dividend records fail. code=%s, err='%s'", stock.Code, err)
 			return err
 		}
@@ -231,6 +269,15 @@ func writeStockDividendRecords(stock *cninfo.Stock, records []*cninfo.DividendRe
 	return nil
 }
 
+func planFields(stock *cninfo.Stock, record *cninfo.DividendRecord) string {
+	plan, err := record.ParsePlan()
+	if err != nil {
+		log.Printf("parse stock dividend plan fail. code=%s, err='%s'", stock.Code, err)
+		return ",,,,"
+	}
+	return fmt.Sprintf("%.6g,%.6g,%.6g,%.6g,%s", plan.CashPerShare(), plan.CashAfterTaxPerShare(), plan.BonusPerShare(), plan.TransferPerShare(), plan.Currency)
+}
+
 func writeStocks(stocks []*cninfo.Stock) error {
 	if len(stocks) == 0 {
 		log.Printf("write stock list skip. no stock")
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/chamzzzzzz/financial/source/cninfo"
+)
+
+var (
+	markets  string
+	limit    int
+	format   string
+	timeout  time.Duration
+	cacheDir string
+)
+
+type result struct {
+	Query   string               `json:"query"`
+	Matches []*cninfo.StockMatch `json:"matches"`
+}
+
+func main() {
+	flag.StringVar(&markets, "markets", "a-share", "markets to look up, separated by comma. eg: a-share,hk")
+	flag.IntVar(&limit, "limit", 10, "max matches to print per query, 0 for all")
+	flag.StringVar(&format, "format", "table", "output format: table, json")
+	flag.DurationVar(&timeout, "timeout", 30*time.Second, "cninfo request timeout")
+	flag.StringVar(&cacheDir, "cache-dir", "", "cninfo response cache dir, empty to disable")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] query...\n\nquery is a stock code, pinyin abbreviation or name. eg: 000001, pfyh, 平安银行\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	source := &cninfo.Source{Timeout: timeout}
+	if cacheDir != "" {
+		source.Cache = cninfo.NewCache(cacheDir)
+	}
+	var stocks []*cninfo.Stock
+	for _, name := range strings.Split(markets, ",") {
+		market, err := cninfo.ParseMarket(name)
+		if err != nil {
+			log.Printf("parse market fail. err='%s'", err)
+			os.Exit(1)
+		}
+		s, err := source.GetMarketStockListContext(ctx, market)
+		if err != nil {
+			log.Printf("get stock list fail. market=%s, err='%s'", name, err)
+			os.Exit(1)
+		}
+		stocks = append(stocks, s...)
+	}
+	index := cninfo.NewStockIndex(stocks)
+
+	var results []*result
+	for _, query := range flag.Args() {
+		matches := index.Search(query, limit)
+		if matches == nil {
+			matches = []*cninfo.StockMatch{}
+		}
+		results = append(results, &result{Query: query, Matches: matches})
+	}
+
+	var err error
+	switch format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(results)
+	default:
+		err = writeTable(results)
+	}
+	if err != nil {
+		log.Printf("write matches fail. err='%s'", err)
+		os.Exit(1)
+	}
+}
+
+func writeTable(results []*result) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "QUERY\tCODE\tNAME\tPINYIN\tMARKET\tSCORE")
+	for _, r := range results {
+		if len(r.Matches) == 0 {
+			fmt.Fprintf(w, "%s\t-\t-\t-\t-\t0\n", r.Query)
+		}
+		for _, m := range r.Matches {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\n", r.Query, m.Stock.Code, m.Stock.Zwjc, m.Stock.Pinyin, m.Stock.Market.Name(), m.Score)
+		}
+	}
+	return w.Flush()
+}
@@ -1,27 +1,56 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"io/fs"
-	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/chamzzzzzz/financial/source"
 	"github.com/chamzzzzzz/financial/source/cninfo"
+	_ "github.com/chamzzzzzz/financial/source/sse"
+	_ "github.com/chamzzzzzz/financial/source/szse"
 	"github.com/urfave/cli/v2"
 )
 
 type AnnualReport struct {
+	Type        string
 	Year        int
 	Title       string
 	URL         string
 	PublishTime int64
+	Kind        string
+	Language    string
+	Revision    string
+	Superseded  bool
+}
+
+type Company struct {
+	FullName            string
+	EnglishName         string
+	Industry            string
+	ListingDate         string
+	EstablishDate       string
+	RegisteredAddress   string
+	OfficeAddress       string
+	LegalRepresentative string
+	MainBusiness        string
+	Website             string
+}
+
+type FinancialStatement struct {
+	Period            string
+	MainIndicators    *cninfo.MainIndicators
+	BalanceSheet      *cninfo.BalanceSheet
+	IncomeStatement   *cninfo.IncomeStatement
+	CashFlowStatement *cninfo.CashFlowStatement
 }
 
 type Stock struct {
@@ -29,13 +58,19 @@ type Stock struct {
 	Name                  string
 	Pinyin                string
 	OrgID                 string
+	Market                string
 	AnnualReportCheckTime int64
 	AnnualReports         []*AnnualReport
+	CompanyCheckTime      int64
+	Company               *Company
+	StatementCheckTime    int64
+	Statements            []*FinancialStatement
 }
 
 type Database struct {
-	Stocks  []*Stock
-	indexes map[string]*Stock `json:"-"`
+	Stocks      []*Stock
+	SyncCursors []*cninfo.SyncCursor `json:",omitempty"`
+	indexes     map[string]*Stock    `json:"-"`
 }
 
 type Option struct {
@@ -47,14 +82,38 @@ type Option struct {
 	AnnualReportDownload       bool
 	AnnualReportDownloadDir    string
 	File                       string
+	Timeout                    time.Duration
+	ReportTypes                []string
+	Markets                    []string
+	CompanyUpdate              bool
+	CompanyCheckInterval       int64
+	StatementUpdate            bool
+	StatementCheckInterval     int64
+	CacheDir                   string
+	Provider                   string
+	ReportKinds                []string
+	ReportLanguages            []string
+	DropSuperseded             bool
+	Workers                    int
+	Sync                       bool
+	Plates                     []string
+	Trades                     []string
 }
 
 type App struct {
-	option   Option
-	database Database
-	start    time.Time
-	end      time.Time
-	years    []int
+	option     Option
+	source     *cninfo.Source
+	provider   source.Provider
+	database   Database
+	start      time.Time
+	end        time.Time
+	years      []int
+	categories []cninfo.Category
+	markets    []cninfo.Market
+	kinds      map[cninfo.ReportKind]bool
+	languages  map[cninfo.Language]bool
+	plates     []cninfo.Plate
+	trades     []cninfo.Trade
 }
 
 func (app *App) Run() error {
@@ -90,7 +149,7 @@ func (app *App) Run() error {
 			},
 			&cli.Int64Flag{
 				Name:        "annual-report-update-interval",
-				Usage:       "annual report update interval",
+				Usage:       "minimum milliseconds between cninfo requests",
 				Destination: &app.option.AnnualReportUpdateInterval,
 				Value:       100,
 			},
@@ -112,10 +171,120 @@ func (app *App) Run() error {
 				Destination: &app.option.File,
 				Value:       "annualreport.db",
 			},
+			&cli.MultiStringFlag{
+				Target: &cli.StringSliceFlag{
+					Name:  "report-types",
+					Usage: "report types: annual, semi-annual, q1, q3",
+				},
+				Value:       []string{"annual"},
+				Destination: &app.option.ReportTypes,
+			},
+			&cli.MultiStringFlag{
+				Target: &cli.StringSliceFlag{
+					Name:  "report-kinds",
+					Usage: "report kinds to keep: full, summary, attachment, other",
+				},
+				Value:       []string{"full", "summary", "attachment", "other"},
+				Destination: &app.option.ReportKinds,
+			},
+			&cli.MultiStringFlag{
+				Target: &cli.StringSliceFlag{
+					Name:  "report-languages",
+					Usage: "report languages to keep: zh, en",
+				},
+				Value:       []string{"zh", "en"},
+				Destination: &app.option.ReportLanguages,
+			},
+			&cli.BoolFlag{
+				Name:        "drop-superseded",
+				Usage:       "drop reports cancelled or re-published later",
+				Destination: &app.option.DropSuperseded,
+				Value:       false,
+			},
+			&cli.MultiStringFlag{
+				Target: &cli.StringSliceFlag{
+					Name:  "markets",
+					Usage: "markets: a-share, hk",
+				},
+				Value:       []string{"a-share"},
+				Destination: &app.option.Markets,
+			},
+			&cli.MultiStringFlag{
+				Target: &cli.StringSliceFlag{
+					Name:  "plates",
+					Usage: "a-share boards to collect: sz, sz-main, chinext, sh, sh-main, star, bse",
+				},
+				Value:       []string{},
+				Destination: &app.option.Plates,
+			},
+			&cli.MultiStringFlag{
+				Target: &cli.StringSliceFlag{
+					Name:  "trades",
+					Usage: "a-share CSRC industry codes to collect, queried market-wide, needs --sync. eg: C, J",
+				},
+				Value:       []string{},
+				Destination: &app.option.Trades,
+			},
+			&cli.BoolFlag{
+				Name:        "company-update",
+				Usage:       "company update",
+				Destination: &app.option.CompanyUpdate,
+				Value:       false,
+			},
+			&cli.Int64Flag{
+				Name:        "company-check-interval",
+				Usage:       "company check interval",
+				Destination: &app.option.CompanyCheckInterval,
+				Value:       30 * 24 * 3600,
+			},
+			&cli.BoolFlag{
+				Name:        "statement-update",
+				Usage:       "financial statement update",
+				Destination: &app.option.StatementUpdate,
+				Value:       false,
+			},
+			&cli.Int64Flag{
+				Name:        "statement-check-interval",
+				Usage:       "financial statement check interval",
+				Destination: &app.option.StatementCheckInterval,
+				Value:       7 * 24 * 3600,
+			},
+			&cli.StringFlag{
+				Name:        "cache-dir",
+				Usage:       "cninfo response cache dir, empty to disable",
+				Destination: &app.option.CacheDir,
+				Value:       "",
+			},
+			&cli.StringFlag{
+				Name:        "provider",
+				Usage:       "periodic report providers to fail over between, separated by comma: " + strings.Join(source.Providers(), ", "),
+				Destination: &app.option.Provider,
+				Value:       "cninfo",
+			},
+			&cli.IntFlag{
+				Name:        "workers",
+				Usage:       "concurrent cninfo requests, still under the request interval",
+				Destination: &app.option.Workers,
+				Value:       cninfo.DefaultBulkWorkers,
+			},
+			&cli.BoolFlag{
+				Name:        "sync",
+				Usage:       "after a first full update, sync the reports of the whole market by day instead of querying every stock",
+				Destination: &app.option.Sync,
+				Value:       false,
+			},
+			&cli.DurationFlag{
+				Name:        "timeout",
+				Usage:       "cninfo request timeout",
+				Destination: &app.option.Timeout,
+				Value:       30 * time.Second,
+			},
 		},
 		Action: app.action,
 	}
-	return c.Run(os.Args)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return c.RunContext(ctx, os.Args)
 }
 
 func (app *App) loadDatabase() error {
@@ -132,6 +301,34 @@ func (app *App) loadDatabase() error {
 	app.database.indexes = make(map[string]*Stock)
 	for _, stock := range app.database.Stocks {
 		app.database.indexes[stock.Code] = stock
+		if stock.Market == "" {
+			stock.Market = string(cninfo.MarketAShare)
+		}
+		var reports []*AnnualReport
+		for _, report := range stock.AnnualReports {
+			if report.Type == "" {
+				report.Type = cninfo.CategoryAnnualReport.Name()
+			}
+			// HK interim reports used to be taken for annual ones.
+			if stock.Market == string(cninfo.MarketHK) && report.Type == cninfo.CategoryAnnualReport.Name() && !cninfo.IsHKCategoryTitle(cninfo.CategoryAnnualReport, report.Title) {
+				fmt.Printf("remove stock %s interim report %s taken for annual\n", stock.Code, report.Title)
+				continue
+			}
+			reports = append(reports, report)
+			// Years used to be the publish year, less one for annual
+			// reports, which is wrong for late and republished reports.
+			if year, ok := cninfo.TitleFiscalYear(report.Title); ok && year != report.Year {
+				fmt.Printf("migrate stock %s annual report %s year %d to %d\n", stock.Code, report.Title, report.Year, year)
+				report.Year = year
+			}
+			if report.Kind == "" {
+				c := cninfo.ClassifyTitle(report.Title)
+				report.Kind = string(c.Kind)
+				report.Language = string(c.Language)
+				report.Revision = string(c.Revision)
+			}
+		}
+		stock.AnnualReports = reports
 	}
 	return nil
 }
@@ -145,37 +342,164 @@ func (app *App) saveDatabase() error {
 	return nil
 }
 
-func (app *App) updateStock() error {
-	source := &cninfo.Source{}
-	stocks, err := source.GetStockList()
-	if err != nil {
-		return err
-	}
-	for _, v := range stocks {
-		if stock := app.database.indexes[v.Code]; stock == nil {
-			stock = &Stock{
-				Code:   v.Code,
-				Name:   v.Zwjc,
-				Pinyin: v.Pinyin,
-				OrgID:  v.OrgID,
+func (app *App) updateStock(ctx context.Context) error {
+	for _, market := range app.markets {
+		stocks, err := app.source.GetMarketStockListContext(ctx, market)
+		if err != nil {
+			return err
+		}
+		for _, v := range stocks {
+			if stock := app.database.indexes[v.Code]; stock == nil {
+				stock = &Stock{
+					Code:   v.Code,
+					Name:   v.Zwjc,
+					Pinyin: v.Pinyin,
+					OrgID:  v.OrgID,
+					Market: string(market),
+				}
+				app.database.Stocks = append(app.database.Stocks, stock)
+				app.database.indexes[stock.Code] = stock
+				fmt.Printf("add stock %+v\n", stock)
 			}
-			app.database.Stocks = append(app.database.Stocks, stock)
-			app.database.indexes[stock.Code] = stock
-			fmt.Printf("add stock %+v\n", stock)
 		}
 	}
 	sort.Slice(app.database.Stocks, func(i, j int) bool { return app.database.Stocks[i].Code < app.database.Stocks[j].Code })
 	return nil
 }
 
-func (app *App) updateAnnualReport() error {
-	now := time.Now()
+func (app *App) updateCompany(ctx context.Context) error {
+	if !app.option.CompanyUpdate {
+		return nil
+	}
+
+	now := cninfo.Now()
+	var stocks []*Stock
+	for _, stock := range app.database.Stocks {
+		if stock.Market != string(cninfo.MarketAShare) {
+			continue
+		}
+		if now.Unix()-stock.CompanyCheckTime < app.option.CompanyCheckInterval {
+			fmt.Printf("skip stock %s company\n", stock.Code)
+			continue
+		}
+		stocks = append(stocks, stock)
+	}
+
+	for i, stock := range stocks {
+		company, err := app.source.GetCompanyContext(ctx, &cninfo.Stock{Code: stock.Code, OrgID: stock.OrgID})
+		if errors.Is(err, cninfo.ErrNotFound) {
+			// Delisted stocks have no company overview.
+			fmt.Printf("skip stock %s company, not found\n", stock.Code)
+			stock.CompanyCheckTime = time.Now().Unix()
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			fmt.Printf("get stock %s company error: %s\n", stock.Code, err)
+			continue
+		}
+		stock.Company = &Company{
+			FullName:            company.FullName,
+			EnglishName:         company.EnglishName,
+			Industry:            company.Industry,
+			ListingDate:         company.ListingDate,
+			EstablishDate:       company.EstablishDate,
+			RegisteredAddress:   company.RegisteredAddress,
+			OfficeAddress:       company.OfficeAddress,
+			LegalRepresentative: company.LegalRepresentative,
+			MainBusiness:        company.MainBusiness,
+			Website:             company.Website,
+		}
+		stock.CompanyCheckTime = time.Now().Unix()
+		fmt.Printf("update stock company %d/%d\n", i+1, len(stocks))
+		if (i+1)%10 == 0 {
+			if err := app.saveDatabase(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := app.saveDatabase(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+func (app *App) updateStatement(ctx context.Context) error {
+	if !app.option.StatementUpdate {
+		return nil
+	}
+
+	now := cninfo.Now()
+	var stocks []*Stock
+	for _, stock := range app.database.Stocks {
+		if stock.Market != string(cninfo.MarketAShare) {
+			continue
+		}
+		if now.Unix()-stock.StatementCheckTime < app.option.StatementCheckInterval {
+			fmt.Printf("skip stock %s financial statement\n", stock.Code)
+			continue
+		}
+		stocks = append(stocks, stock)
+	}
+
+	for i, stock := range stocks {
+		f, err := app.source.GetFinancialStatementsContext(ctx, &cninfo.Stock{Code: stock.Code, OrgID: stock.OrgID})
+		if errors.Is(err, cninfo.ErrNotFound) {
+			fmt.Printf("skip stock %s financial statement, not found\n", stock.Code)
+			stock.StatementCheckTime = time.Now().Unix()
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			fmt.Printf("get stock %s financial statement error: %s\n", stock.Code, err)
+			continue
+		}
+		// Periods are latest first, so the saved order does not depend on map order.
+		var statements []*FinancialStatement
+		for _, period := range f.Periods() {
+			statements = append(statements, &FinancialStatement{
+				Period:            period,
+				MainIndicators:    f.MainIndicators[period],
+				BalanceSheet:      f.BalanceSheets[period],
+				IncomeStatement:   f.IncomeStatements[period],
+				CashFlowStatement: f.CashFlowStatements[period],
+			})
+		}
+		stock.Statements = statements
+		stock.StatementCheckTime = time.Now().Unix()
+		fmt.Printf("update stock financial statement %d/%d\n", i+1, len(stocks))
+		if (i+1)%10 == 0 {
+			if err := app.saveDatabase(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := app.saveDatabase(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+func (app *App) updateAnnualReport(ctx context.Context) error {
+	now := cninfo.Now()
 	var stocks []*Stock
 	for _, stock := range app.database.Stocks {
+		if !app.collectStock(stock) {
+			continue
+		}
 		if now.Unix()-stock.AnnualReportCheckTime < app.option.AnnualReportCheckInterval {
 			fmt.Printf("skip stock %s annual report\n", stock.Code)
 			continue
 		}
+		// Synced stocks are kept up to date by syncAnnualReport, new ones
+		// still need a full update.
+		if app.option.Sync && stock.AnnualReportCheckTime != 0 && app.synced(cninfo.Market(stock.Market)) {
+			continue
+		}
 		stocks = append(stocks, stock)
 	}
 
@@ -189,17 +513,19 @@ func (app *App) updateAnnualReport() error {
 				max = year
 			}
 		}
-		app.start = time.Date(min, 1, 1, 0, 0, 0, 0, time.Local)
-		app.end = time.Date(max, 12, 31, 0, 0, 0, 0, time.Local)
-		if app.start.Before(time.Date(2000, 1, 1, 0, 0, 0, 0, time.Local)) {
-			app.start = time.Date(2000, 1, 1, 0, 0, 0, 0, time.Local)
+		// Reports of a fiscal year are published until the year after.
+		app.years = app.option.SpecifiedYears
+		app.start = time.Date(min, 1, 1, 0, 0, 0, 0, cninfo.Location)
+		app.end = time.Date(max+1, 12, 31, 0, 0, 0, 0, cninfo.Location)
+		if app.start.Before(time.Date(2000, 1, 1, 0, 0, 0, 0, cninfo.Location)) {
+			app.start = time.Date(2000, 1, 1, 0, 0, 0, 0, cninfo.Location)
 		}
 		if app.end.After(now) {
 			app.end = now
 		}
 	} else {
 		if app.option.SinceYear2000 {
-			app.start = time.Date(2000, 1, 1, 0, 0, 0, 0, time.Local)
+			app.start = time.Date(2000, 1, 1, 0, 0, 0, 0, cninfo.Location)
 			app.end = now
 		} else if app.option.LatestThreeYears {
 			app.start = now.AddDate(-3, 0, 0)
@@ -215,72 +541,241 @@ func (app *App) updateAnnualReport() error {
 		}
 	}
 
-	i := 0
+	// cninfo only tells the industry of stocks by its queries, so reports of
+	// some industries are collected market-wide.
+	if len(app.trades) > 0 {
+		return app.syncAnnualReport(ctx, now)
+	}
+
+	var list []*cninfo.Stock
+	m := make(map[*cninfo.Stock]*Stock)
 	for _, stock := range stocks {
-		reports, err := app.getAnnualReports(stock)
-		if err != nil {
-			return err
-		}
-		for _, report := range reports {
-			dup := false
-			for _, v := range stock.AnnualReports {
-				if *v == *report {
-					fmt.Printf("skip stock %s dup annual report %+v\n", stock.Code, report)
-					dup = true
-					break
-				}
-			}
-			if dup {
-				continue
-			}
-			stock.AnnualReports = append(stock.AnnualReports, report)
-			fmt.Printf("add stock %s annual report %+v\n", stock.Code, report)
+		s := app.cninfoStock(stock)
+		list = append(list, s)
+		m[s] = stock
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	i, failed := 0, 0
+	for r := range cninfo.Bulk(ctx, list, &cninfo.BulkOptions{Workers: app.option.Workers}, app.getAnnualReports) {
+		stock, reports := m[r.Stock], r.Value
+		i++
+		if r.Err != nil {
+			failed++
+			fmt.Printf("update stock annual report %d/%d failed\n", i, len(stocks))
+			continue
 		}
+		app.mergeAnnualReports(stock, reports)
 		stock.AnnualReportCheckTime = time.Now().Unix()
 
-		i++
 		fmt.Printf("update stock annual report %d/%d\n", i, len(stocks))
 		if i%10 == 0 {
-			fmt.Printf("sleep %d milliseconds\n", app.option.AnnualReportUpdateInterval*2)
-			time.Sleep(time.Duration(app.option.AnnualReportUpdateInterval * 2 * int64(time.Millisecond)))
 			if err := app.saveDatabase(); err != nil {
 				return err
 			}
-		} else {
-			fmt.Printf("sleep %d milliseconds\n", app.option.AnnualReportUpdateInterval)
-			time.Sleep(time.Duration(app.option.AnnualReportUpdateInterval * int64(time.Millisecond)))
 		}
 	}
 	if err := app.saveDatabase(); err != nil {
 		return err
 	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("update annual report of %d stocks failed", failed)
+	}
+	if app.option.Sync {
+		return app.syncAnnualReport(ctx, now)
+	}
 	return nil
 }
 
-func (app *App) getAnnualReports(stock *Stock) ([]*AnnualReport, error) {
-	source := &cninfo.Source{}
-	announcements, err := source.GetAnnualReportAnnoucements(&cninfo.Stock{Code: stock.Code, OrgID: stock.OrgID}, app.start, app.end)
-	if err != nil {
-		for i := 0; i < 3; i++ {
-			fmt.Printf("get stock %s annual report error: %s\n", stock.Code, err)
-			fmt.Printf("retry after 5 seconds\n")
-			time.Sleep(time.Second * 5)
-			announcements, err = source.GetAnnualReportAnnoucements(&cninfo.Stock{Code: stock.Code, OrgID: stock.OrgID}, app.start, app.end)
-			if err == nil {
+func (app *App) mergeAnnualReports(stock *Stock, reports []*AnnualReport) {
+	for _, report := range reports {
+		dup := false
+		for _, v := range stock.AnnualReports {
+			if v.URL == report.URL {
+				if *v == *report {
+					fmt.Printf("skip stock %s dup annual report %+v\n", stock.Code, report)
+				} else {
+					*v = *report
+					fmt.Printf("update stock %s annual report %+v\n", stock.Code, report)
+				}
+				dup = true
 				break
 			}
-			if i == 2 {
-				return nil, err
+		}
+		if dup {
+			continue
+		}
+		stock.AnnualReports = append(stock.AnnualReports, report)
+		fmt.Printf("add stock %s annual report %+v\n", stock.Code, report)
+	}
+	var kept []*AnnualReport
+	for _, report := range stock.AnnualReports {
+		if app.keepReport(report) {
+			kept = append(kept, report)
+		} else {
+			fmt.Printf("remove stock %s annual report %+v\n", stock.Code, report)
+		}
+	}
+	stock.AnnualReports = kept
+}
+
+func (app *App) syncCursor(market cninfo.Market, category cninfo.Category) *cninfo.SyncCursor {
+	for _, cursor := range app.database.SyncCursors {
+		if cursor.Matches(market, category, app.plates, app.trades) {
+			return cursor
+		}
+	}
+	return nil
+}
+
+// synced reports whether every collected category of market has a sync
+// cursor, i.e. its stocks had a full update before.
+func (app *App) synced(market cninfo.Market) bool {
+	for _, category := range app.categories {
+		if app.syncCursor(market, category) == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// syncAnnualReport gets the reports of the whole market published since the
+// sync cursors, which is a few requests a day instead of one per stock. The
+// markets without cursors just had a full update, so their cursors start at
+// the earliest check of their stocks. With trades, markets without cursors
+// are updated here from start to end once, then synced.
+func (app *App) syncAnnualReport(ctx context.Context, now time.Time) error {
+	orgs := make(map[string]*Stock)
+	checked := make(map[cninfo.Market]time.Time)
+	for _, stock := range app.database.Stocks {
+		if stock.OrgID != "" {
+			orgs[stock.OrgID] = stock
+		}
+		market := cninfo.Market(stock.Market)
+		if t := time.Unix(stock.AnnualReportCheckTime, 0); stock.AnnualReportCheckTime != 0 && app.collectStock(stock) && (checked[market].IsZero() || t.Before(checked[market])) {
+			checked[market] = t
+		}
+	}
+	for _, market := range app.markets {
+		for _, category := range app.categories {
+			var announcements []*cninfo.Announcement
+			var err error
+			cursor := app.syncCursor(market, category)
+			switch {
+			case cursor != nil && app.option.Sync:
+				announcements, err = app.source.Sync(ctx, cursor, now)
+			case cursor == nil && len(app.trades) > 0:
+				q := &cninfo.SearchQuery{Market: market, Category: category, Plates: app.plates, Trades: app.trades}
+				announcements, err = app.source.GetAnnouncementsContext(ctx, q, app.start, app.end)
+			}
+			if err != nil {
+				fmt.Printf("sync %s %s report error: %s\n", market, category.Name(), err)
+				return err
+			}
+			if cursor == nil && app.option.Sync {
+				start := now
+				if t := checked[market]; len(app.trades) == 0 && !t.IsZero() && t.Before(now) {
+					start = t
+				}
+				cursor = cninfo.NewSyncCursor(market, category, start)
+				cursor.Plates, cursor.Trades = app.plates, app.trades
+				app.database.SyncCursors = append(app.database.SyncCursors, cursor)
+				fmt.Printf("start sync %s %s report at %s\n", market, category.Name(), cursor.Date)
+			}
+			for code, announcements := range cninfo.GroupAnnouncements(announcements) {
+				stock := app.database.indexes[code]
+				if stock == nil {
+					stock = orgs[announcements[0].OrgID]
+				}
+				if stock == nil || !app.collectStock(stock) {
+					fmt.Printf("skip stock %s %s report, not collected\n", code, category.Name())
+					continue
+				}
+				app.mergeAnnualReports(stock, app.periodicReports(announcements, category))
+			}
+			if cursor != nil {
+				fmt.Printf("sync %s %s report to %s\n", market, category.Name(), cursor.Date)
 			}
 		}
 	}
+	return app.saveDatabase()
+}
+
+// keepReport reports whether report is one of the variants to collect. No
+// kinds or languages set means all of them.
+func (app *App) keepReport(report *AnnualReport) bool {
+	if len(app.kinds) > 0 && !app.kinds[cninfo.ReportKind(report.Kind)] {
+		return false
+	}
+	if len(app.languages) > 0 && !app.languages[cninfo.Language(report.Language)] {
+		return false
+	}
+	return !app.option.DropSuperseded || !report.Superseded
+}
+
+// collectStock reports whether stock is of the markets and plates to
+// collect. No plates set means all of them.
+func (app *App) collectStock(stock *Stock) bool {
+	if !app.collectMarket(stock.Market) {
+		return false
+	}
+	if len(app.plates) == 0 {
+		return true
+	}
+	for _, plate := range app.plates {
+		if plate.Contains(stock.Code) {
+			return true
+		}
+	}
+	return false
+}
+
+func (app *App) collectMarket(market string) bool {
+	for _, m := range app.markets {
+		if string(m) == market {
+			return true
+		}
+	}
+	return false
+}
+
+func (app *App) cninfoStock(stock *Stock) *cninfo.Stock {
+	return &cninfo.Stock{Code: stock.Code, OrgID: stock.OrgID, Zwjc: stock.Name, Pinyin: stock.Pinyin, Market: cninfo.Market(stock.Market)}
+}
+
+func (app *App) getAnnualReports(ctx context.Context, stock *cninfo.Stock) ([]*AnnualReport, error) {
 	var reports []*AnnualReport
-	for _, announcement := range announcements {
+	for _, category := range app.categories {
+		r, err := app.getPeriodicReports(ctx, stock, category)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, r...)
+	}
+	return reports, nil
+}
+
+func (app *App) getPeriodicReports(ctx context.Context, stock *cninfo.Stock, category cninfo.Category) ([]*AnnualReport, error) {
+	announcements, err := app.provider.GetPeriodicReportAnnouncementsContext(ctx, stock, category, app.start, app.end)
+	if err != nil {
+		fmt.Printf("get stock %s %s report error: %s\n", stock.Code, category.Name(), err)
+		return nil, err
+	}
+	return app.periodicReports(announcements, category), nil
+}
+
+func (app *App) periodicReports(announcements []*cninfo.Announcement, category cninfo.Category) []*AnnualReport {
+	classes := cninfo.ClassifyAnnouncements(announcements)
+	var reports []*AnnualReport
+	for i, announcement := range announcements {
 		if strings.ToUpper(announcement.AdjunctType) != "PDF" {
 			continue
 		}
 
-		year := time.Unix(announcement.AnnouncementTime/1000, 0).Year() - 1
+		year := announcement.FiscalYear(category)
 		matched := false
 		for _, y := range app.years {
 			if y == year {
@@ -293,17 +788,25 @@ func (app *App) getAnnualReports(stock *Stock) ([]*AnnualReport, error) {
 		}
 
 		report := &AnnualReport{
+			Type:        category.Name(),
 			Year:        year,
 			Title:       announcement.AnnouncementTitle,
-			URL:         "http://static.cninfo.com.cn/" + announcement.AdjunctURL,
+			URL:         app.source.AdjunctURL(announcement),
 			PublishTime: announcement.AnnouncementTime,
+			Kind:        string(classes[i].Kind),
+			Language:    string(classes[i].Language),
+			Revision:    string(classes[i].Revision),
+			Superseded:  classes[i].Superseded,
+		}
+		if !app.keepReport(report) {
+			continue
 		}
 		reports = append(reports, report)
 	}
-	return reports, nil
+	return reports
 }
 
-func (app *App) downloadAnnualReport() error {
+func (app *App) downloadAnnualReport(ctx context.Context) error {
 	if !app.option.AnnualReportDownload {
 		return nil
 	}
@@ -333,10 +836,10 @@ func (app *App) downloadAnnualReport() error {
 				return err
 			}
 
-			err = app.downloadURL(report.URL, file)
+			err = source.DownloadAdjunctFile(ctx, app.provider, &cninfo.Announcement{AdjunctURL: report.URL, AdjunctType: "PDF"}, file)
 			if err != nil {
 				fmt.Printf("download annual report %s, %s failed: %s\n", report.URL, file, err)
-				if fmt.Sprintf("%s", err) == "status code:404" {
+				if errors.Is(err, cninfo.ErrNotFound) {
 					continue
 				} else {
 					return err
@@ -348,47 +851,113 @@ func (app *App) downloadAnnualReport() error {
 	return nil
 }
 
-func (app *App) downloadURL(URL, file string) error {
-	resp, err := http.Get(URL)
+func (app *App) action(c *cli.Context) error {
+	config := &source.Config{
+		Timeout:  app.option.Timeout,
+		Interval: time.Duration(app.option.AnnualReportUpdateInterval) * time.Millisecond,
+		CacheDir: app.option.CacheDir,
+	}
+	p, err := source.Open("cninfo", config)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("status code:%d", resp.StatusCode)
+	app.source = p.(*cninfo.Source)
+	if app.provider, err = source.OpenFailover(app.option.Provider, config, map[string]source.Provider{"cninfo": p}); err != nil {
+		return err
 	}
-	b, err := io.ReadAll(resp.Body)
+	for _, name := range app.option.ReportTypes {
+		category, err := cninfo.ParseCategory(name)
+		if err != nil {
+			return err
+		}
+		if !category.IsPeriodicReport() {
+			return fmt.Errorf("%s is not a periodic report type", name)
+		}
+		app.categories = append(app.categories, category)
+	}
+	app.kinds = make(map[cninfo.ReportKind]bool)
+	for _, name := range app.option.ReportKinds {
+		kind, err := cninfo.ParseReportKind(name)
+		if err != nil {
+			return err
+		}
+		app.kinds[kind] = true
+	}
+	app.languages = make(map[cninfo.Language]bool)
+	for _, name := range app.option.ReportLanguages {
+		language, err := cninfo.ParseLanguage(name)
+		if err != nil {
+			return err
+		}
+		app.languages[language] = true
+	}
+	for _, name := range app.option.Markets {
+		market, err := cninfo.ParseMarket(name)
+		if err != nil {
+			return err
+		}
+		if market != cninfo.MarketAShare && market != cninfo.MarketHK {
+			return fmt.Errorf("%s market has no periodic reports", name)
+		}
+		app.markets = append(app.markets, market)
+	}
+	for _, name := range app.option.Plates {
+		plate, err := cninfo.ParsePlate(name)
+		if err != nil {
+			return err
+		}
+		app.plates = append(app.plates, plate)
+	}
+	for _, name := range app.option.Trades {
+		trade, err := cninfo.ParseTrade(name)
+		if err != nil {
+			return err
+		}
+		app.trades = append(app.trades, trade)
+	}
+	// Without a sync cursor every run would query the whole window of the
+	// trades again, regardless of the check interval.
+	if len(app.trades) > 0 && !app.option.Sync {
+		return errors.New("trades need --sync")
+	}
+	if len(app.plates) > 0 || len(app.trades) > 0 {
+		for _, market := range app.markets {
+			if market != cninfo.MarketAShare {
+				return fmt.Errorf("plates and trades are only of the %s market", cninfo.MarketAShare.Name())
+			}
+		}
+	}
+	err = app.loadDatabase()
 	if err != nil {
 		return err
 	}
-	if len(b) != int(resp.ContentLength) {
-		return fmt.Errorf("content length not match %d, %d", resp.ContentLength, len(b))
+
+	err = app.updateStock(c.Context)
+	if err != nil {
+		return err
 	}
-	return os.WriteFile(file, b, 0666)
-}
 
-func (app *App) action(c *cli.Context) error {
-	err := app.loadDatabase()
+	err = app.saveDatabase()
 	if err != nil {
 		return err
 	}
 
-	err = app.updateStock()
+	err = app.updateCompany(c.Context)
 	if err != nil {
 		return err
 	}
 
-	err = app.saveDatabase()
+	err = app.updateAnnualReport(c.Context)
 	if err != nil {
 		return err
 	}
 
-	err = app.updateAnnualReport()
+	err = app.updateStatement(c.Context)
 	if err != nil {
 		return err
 	}
 
-	err = app.downloadAnnualReport()
+	err = app.downloadAnnualReport(c.Context)
 	if err != nil {
 		return err
 	}
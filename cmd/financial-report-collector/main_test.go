@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chamzzzzzz/financial/source/cninfo"
+	"github.com/chamzzzzzz/financial/source/cninfo/cninfotest"
+	"github.com/chamzzzzzz/financial/source/cninfo/replay/replaytest"
+)
+
+func TestGetAnnualReports(t *testing.T) {
+	s := replaytest.NewSource(t, filepath.Join("..", "..", "source", "cninfo", "testdata", "annual_report_announcements.json"))
+	app := &App{
+		source:     s,
+		provider:   s,
+		start:      time.Date(2021, 1, 1, 0, 0, 0, 0, cninfo.Location),
+		end:        time.Date(2023, 6, 30, 0, 0, 0, 0, cninfo.Location),
+		years:      []int{2021, 2022},
+		categories: []cninfo.Category{cninfo.CategoryAnnualReport},
+	}
+	reports, err := app.getAnnualReports(context.Background(), &cninfo.Stock{Code: "000001", OrgID: "gssz0000001", Market: cninfo.MarketAShare})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 22 {
+		t.Fatalf("got %d reports, want 22 of 2021 and 2022", len(reports))
+	}
+	for _, report := range reports {
+		if report.Type != "annual" {
+			t.Errorf("got report type %s, want annual", report.Type)
+		}
+		if report.Year != 2021 && report.Year != 2022 {
+			t.Errorf("got report year %d of %s", report.Year, report.Title)
+		}
+		if !strings.HasPrefix(report.URL, "http://static.cninfo.com.cn/finalpage/") {
+			t.Errorf("got report url %s", report.URL)
+		}
+	}
+}
+
+func TestGetAnnualReportsVariants(t *testing.T) {
+	s := replaytest.NewSource(t, filepath.Join("..", "..", "source", "cninfo", "testdata", "annual_report_announcements.json"))
+	app := &App{
+		option:     Option{DropSuperseded: true},
+		source:     s,
+		provider:   s,
+		start:      time.Date(2021, 1, 1, 0, 0, 0, 0, cninfo.Location),
+		end:        time.Date(2023, 6, 30, 0, 0, 0, 0, cninfo.Location),
+		years:      []int{2021, 2022},
+		categories: []cninfo.Category{cninfo.CategoryAnnualReport},
+		kinds:      map[cninfo.ReportKind]bool{cninfo.KindFull: true},
+		languages:  map[cninfo.Language]bool{cninfo.LanguageChinese: true},
+	}
+	reports, err := app.getAnnualReports(context.Background(), &cninfo.Stock{Code: "000001", OrgID: "gssz0000001", Market: cninfo.MarketAShare})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for _, report := range reports {
+		titles = append(titles, report.Title)
+	}
+	if got, want := strings.Join(titles, ","), "2021年年度报告,2022年年度报告（更新后）"; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
+
+func TestLoadDatabaseMigratesYears(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "annualreport.db")
+	db := `{"Stocks":[{"Code":"000001","AnnualReports":[{"Year":2021,"Title":"2019年年度报告（更正后）"},{"Year":2021,"Title":"年度报告"}]}]}`
+	if err := os.WriteFile(file, []byte(db), 0644); err != nil {
+		t.Fatal(err)
+	}
+	app := &App{option: Option{File: file}}
+	if err := app.loadDatabase(); err != nil {
+		t.Fatal(err)
+	}
+	reports := app.database.Stocks[0].AnnualReports
+	if reports[0].Year != 2019 || reports[1].Year != 2021 {
+		t.Fatalf("got years %d and %d, want 2019 and 2021", reports[0].Year, reports[1].Year)
+	}
+	if reports[0].Type != "annual" || reports[0].Kind != "full" || reports[0].Revision != "corrected" {
+		t.Fatalf("got report %+v, want a corrected full annual report", reports[0])
+	}
+}
+
+func TestSyncAnnualReport(t *testing.T) {
+	published := time.Date(2023, 4, 20, 18, 0, 0, 0, cninfo.Location)
+	server := cninfotest.NewServer(&cninfotest.Dataset{
+		Announcements: map[cninfo.Category][]*cninfo.Announcement{
+			cninfo.CategoryAnnualReport: {
+				{AnnouncementID: "1", SecCode: "000001", OrgID: "gssz0000001", AnnouncementTitle: "2022年年度报告", AdjunctURL: "finalpage/2023-04-20/1.PDF", AdjunctType: "PDF", AnnouncementTime: published.UnixMilli()},
+				{AnnouncementID: "2", SecCode: "000002", OrgID: "gssz0000002", AnnouncementTitle: "2022年年度报告", AdjunctURL: "finalpage/2023-04-20/2.PDF", AdjunctType: "PDF", AnnouncementTime: published.UnixMilli()},
+			},
+		},
+	})
+	defer server.Close()
+	s := server.Source()
+	stock := &Stock{Code: "000001", OrgID: "gssz0000001", Market: string(cninfo.MarketAShare), AnnualReportCheckTime: 1}
+	app := &App{
+		option:     Option{File: filepath.Join(t.TempDir(), "annualreport.db"), Sync: true},
+		source:     s,
+		provider:   s,
+		years:      []int{2022},
+		categories: []cninfo.Category{cninfo.CategoryAnnualReport},
+		markets:    []cninfo.Market{cninfo.MarketAShare},
+		database: Database{
+			Stocks:      []*Stock{stock},
+			SyncCursors: []*cninfo.SyncCursor{cninfo.NewSyncCursor(cninfo.MarketAShare, cninfo.CategoryAnnualReport, published.AddDate(0, 0, -1))},
+			indexes:     map[string]*Stock{"000001": stock},
+		},
+	}
+	if !app.synced(cninfo.MarketAShare) {
+		t.Fatal("got a-share not synced")
+	}
+	if err := app.syncAnnualReport(context.Background(), published.AddDate(0, 0, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if len(stock.AnnualReports) != 1 || stock.AnnualReports[0].Year != 2022 {
+		t.Fatalf("got reports %+v, want the 2022 annual report", stock.AnnualReports)
+	}
+	if got := app.database.SyncCursors[0].Date; got != "2023-04-21" {
+		t.Fatalf("got cursor date %s, want 2023-04-21", got)
+	}
+}
+
+func TestUpdateCompany(t *testing.T) {
+	s := replaytest.NewSource(t, filepath.Join("..", "..", "source", "cninfo", "testdata", "company.json"))
+	a := string(cninfo.MarketAShare)
+	delisted, failed, stock := &Stock{Code: "000013", Market: a}, &Stock{Code: "999999", Market: a}, &Stock{Code: "000001", Market: a}
+	app := &App{
+		option:   Option{File: filepath.Join(t.TempDir(), "annualreport.db"), CompanyUpdate: true, CompanyCheckInterval: 3600},
+		source:   s,
+		database: Database{Stocks: []*Stock{delisted, failed, stock}},
+	}
+	if err := app.updateCompany(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if stock.Company == nil || stock.Company.FullName != "平安银行股份有限公司" {
+		t.Fatalf("got company %+v, want the one of 000001", stock.Company)
+	}
+	if delisted.Company != nil || delisted.CompanyCheckTime == 0 {
+		t.Fatalf("got delisted stock %+v, want it checked without company", delisted)
+	}
+	if failed.CompanyCheckTime != 0 {
+		t.Fatalf("got failed stock checked, want it checked again next run")
+	}
+}
+
+func TestUpdateStatement(t *testing.T) {
+	s := replaytest.NewSource(t, filepath.Join("..", "..", "source", "cninfo", "testdata", "statement.json"))
+	a := string(cninfo.MarketAShare)
+	failed, stock := &Stock{Code: "999999", Market: a}, &Stock{Code: "000001", Market: a}
+	app := &App{
+		option:   Option{File: filepath.Join(t.TempDir(), "annualreport.db"), StatementUpdate: true, StatementCheckInterval: 3600},
+		source:   s,
+		database: Database{Stocks: []*Stock{failed, stock}},
+	}
+	if err := app.updateStatement(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(stock.Statements) != 2 || stock.Statements[0].Period != "2022-12-31" || stock.Statements[1].Period != "2021-12-31" {
+		t.Fatalf("got statements %+v, want 2022-12-31 and 2021-12-31", stock.Statements)
+	}
+	if stock.Statements[0].IncomeStatement == nil || stock.Statements[0].IncomeStatement.Revenue != 179895000000 {
+		t.Fatalf("got income statement %+v of 2022-12-31", stock.Statements[0].IncomeStatement)
+	}
+	if failed.StatementCheckTime != 0 {
+		t.Fatalf("got failed stock checked, want it checked again next run")
+	}
+}
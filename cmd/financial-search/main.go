@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/chamzzzzzz/financial/source/cninfo"
+)
+
+var (
+	market   string
+	code     string
+	keyword  string
+	category string
+	plate    string
+	trade    string
+	start    string
+	end      string
+	sortName string
+	sortType string
+	format   string
+	limit    int
+	timeout  time.Duration
+	cacheDir string
+)
+
+func main() {
+	flag.StringVar(&market, "market", "a-share", "market: a-share, hk, fund, bond")
+	flag.StringVar(&code, "code", "", "stock code, pinyin or name, empty to search the whole market")
+	flag.StringVar(&keyword, "keyword", "", "search keyword")
+	flag.StringVar(&category, "category", "", "announcement category. eg: annual, semi-annual, q1, q3")
+	flag.StringVar(&plate, "plate", "", "boards separated by comma: "+plateNames())
+	flag.StringVar(&trade, "trade", "", "CSRC industry codes separated by comma. eg: C,J")
+	flag.StringVar(&start, "start", "", "start date. eg: 2022-01-01")
+	flag.StringVar(&end, "end", "", "end date. eg: 2022-12-31")
+	flag.StringVar(&sortName, "sort", "", "sort by: time, code")
+	flag.StringVar(&sortType, "order", "", "sort order: asc, desc")
+	flag.StringVar(&format, "format", "table", "output format: table, json, csv")
+	flag.IntVar(&limit, "limit", 0, "max announcements to print, 0 for all")
+	flag.DurationVar(&timeout, "timeout", 30*time.Second, "cninfo request timeout")
+	flag.StringVar(&cacheDir, "cache-dir", "", "cninfo response cache dir, empty to disable")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	source := &cninfo.Source{Timeout: timeout}
+	if cacheDir != "" {
+		source.Cache = cninfo.NewCache(cacheDir)
+	}
+	q, err := query(ctx, source)
+	if err != nil {
+		log.Printf("build query fail. err='%s'", err)
+		os.Exit(1)
+	}
+
+	var announcements []*cninfo.Announcement
+	it := source.Search(ctx, q)
+	for it.Next() {
+		announcements = append(announcements, it.Announcement())
+		if limit > 0 && len(announcements) >= limit {
+			break
+		}
+	}
+	if err := it.Err(); err != nil {
+		log.Printf("search fail. err='%s'", err)
+		os.Exit(1)
+	}
+
+	switch format {
+	case "json":
+		err = writeJSON(announcements)
+	case "csv":
+		err = writeCSV(announcements)
+	default:
+		err = writeTable(announcements)
+	}
+	if err != nil {
+		log.Printf("write announcements fail. err='%s'", err)
+		os.Exit(1)
+	}
+}
+
+func query(ctx context.Context, source *cninfo.Source) (*cninfo.SearchQuery, error) {
+	m, err := cninfo.ParseMarket(market)
+	if err != nil {
+		return nil, err
+	}
+	q := &cninfo.SearchQuery{
+		Market:   m,
+		Keyword:  keyword,
+		SortName: sortName,
+		SortType: sortType,
+	}
+	if q.Plates, err = cninfo.ParsePlates(plate); err != nil {
+		return nil, err
+	}
+	if q.Trades, err = cninfo.ParseTrades(trade); err != nil {
+		return nil, err
+	}
+	if category != "" {
+		c, err := cninfo.ParseCategory(category)
+		if err != nil {
+			return nil, err
+		}
+		q.Category = c
+	}
+	if start != "" {
+		t, err := time.ParseInLocation("2006-01-02", start, cninfo.Location)
+		if err != nil {
+			return nil, err
+		}
+		q.Start = t
+	}
+	if end != "" {
+		t, err := time.ParseInLocation("2006-01-02", end, cninfo.Location)
+		if err != nil {
+			return nil, err
+		}
+		q.End = t
+	}
+	if code != "" {
+		stocks, err := source.GetMarketStockListContext(ctx, m)
+		if err != nil {
+			return nil, err
+		}
+		if q.Stock, err = cninfo.NewStockIndex(stocks).Resolve(code); err != nil {
+			return nil, err
+		}
+	}
+	return q, q.Validate()
+}
+
+func plateNames() string {
+	var names []string
+	for _, plate := range cninfo.Plates {
+		names = append(names, plate.Name())
+	}
+	return strings.Join(names, ", ")
+}
+
+func publishDate(announcement *cninfo.Announcement) string {
+	return announcement.Time().Format("2006-01-02")
+}
+
+func writeTable(announcements []*cninfo.Announcement) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "DATE\tCODE\tNAME\tTITLE\tURL")
+	for _, announcement := range announcements {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", publishDate(announcement), announcement.SecCode, announcement.SecName, announcement.AnnouncementTitle, announcement.AdjunctURL)
+	}
+	return w.Flush()
+}
+
+func writeJSON(announcements []*cninfo.Announcement) error {
+	if announcements == nil {
+		announcements = []*cninfo.Announcement{}
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(announcements)
+}
+
+func writeCSV(announcements []*cninfo.Announcement) error {
+	w := csv.NewWriter(os.Stdout)
+	w.Write([]string{"date", "code", "name", "id", "title", "url"})
+	for _, announcement := range announcements {
+		w.Write([]string{publishDate(announcement), announcement.SecCode, announcement.SecName, announcement.AnnouncementID, announcement.AnnouncementTitle, announcement.AdjunctURL})
+	}
+	w.Flush()
+	return w.Error()
+}
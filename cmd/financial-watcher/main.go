@@ -2,6 +2,8 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -9,13 +11,17 @@ import (
 	"net"
 	"net/smtp"
 	"os"
+	"os/signal"
 	"sort"
 	"strconv"
 	"strings"
 	"text/template"
 	"time"
 
+	"github.com/chamzzzzzz/financial/source"
 	"github.com/chamzzzzzz/financial/source/cninfo"
+	_ "github.com/chamzzzzzz/financial/source/sse"
+	_ "github.com/chamzzzzzz/financial/source/szse"
 )
 
 var (
@@ -24,35 +30,92 @@ var (
 	pass = os.Getenv("FINANCIAL_WATCHER_SMTP_PASS")
 	to   = os.Getenv("FINANCIAL_WATCHER_SMTP_TO")
 	tpl  = template.Must(template.New("").Parse("From: {{.From}}\r\nTo: {{.To}}\r\nSubject: {{.Subject}}\r\n\r\n{{.Body}}"))
+
+	parsePlan bool
 )
 
 func main() {
-	var w1, w2, w3 bool
+	var w1, w2, w3, w4 bool
+	var timeout time.Duration
+	var markets string
+	var cacheDir string
+	var providerName string
+	var crossCheck string
+	var workers int
+	var syncReport bool
+	var plateNames, tradeNames string
 	flag.BoolVar(&w1, "stock", false, "stock code")
 	flag.BoolVar(&w2, "report", false, "stock report")
 	flag.BoolVar(&w3, "dividend", false, "stock dividend")
+	flag.BoolVar(&w4, "shareholder", false, "stock shareholder")
 	flag.StringVar(&addr, "addr", addr, "notification smtp addr")
 	flag.StringVar(&user, "user", user, "notification smtp user")
 	flag.StringVar(&pass, "pass", pass, "notification smtp pass")
 	flag.StringVar(&to, "to", to, "notification smtp to")
+	flag.DurationVar(&timeout, "timeout", 30*time.Second, "cninfo request timeout")
+	flag.StringVar(&markets, "markets", "a-share", "markets of stock list, separated by comma. eg: a-share,hk")
+	flag.StringVar(&cacheDir, "cache-dir", "", "cninfo response cache dir, empty to disable")
+	flag.StringVar(&providerName, "provider", "cninfo", "report and dividend providers to fail over between, separated by comma: "+strings.Join(source.Providers(), ", "))
+	flag.IntVar(&workers, "workers", cninfo.DefaultBulkWorkers, "concurrent report and dividend requests")
+	flag.StringVar(&crossCheck, "cross-check", "", "providers to cross-check report announcements against, separated by comma, empty to disable")
+	flag.BoolVar(&syncReport, "sync", false, "sync report announcements of the whole market by day, keeping the cursor in sync.txt, instead of querying every watch stock")
+	flag.StringVar(&plateNames, "plates", "", "a-share boards of watch stocks to watch, separated by comma, empty for all: sz, sz-main, chinext, sh, sh-main, star, bse")
+	flag.StringVar(&tradeNames, "trades", "", "a-share CSRC industry codes to sync report announcements of, separated by comma, needs -sync. watch stocks of other industries are queried alone. eg: C,J")
+	flag.BoolVar(&parsePlan, "parse-plan", false, "append parsed dividend plan per share (cash, cash after tax, bonus, transfer, currency) to dividend files")
 	flag.Parse()
 
-	source := &cninfo.Source{}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	plates, err := cninfo.ParsePlates(plateNames)
+	if err != nil {
+		log.Printf("parse plates fail. err='%s'", err)
+		return
+	}
+	trades, err := cninfo.ParseTrades(tradeNames)
+	if err != nil {
+		log.Printf("parse trades fail. err='%s'", err)
+		return
+	}
+	if len(trades) > 0 && !syncReport {
+		log.Printf("trades need -sync.")
+		return
+	}
+
+	config := &source.Config{Timeout: timeout, CacheDir: cacheDir}
+	p, err := source.Open("cninfo", config)
+	if err != nil {
+		log.Printf("open cninfo fail. err='%s'", err)
+		return
+	}
+	cninfoSource := p.(*cninfo.Source)
+	opened := map[string]source.Provider{"cninfo": p}
+	provider, err := source.OpenFailover(providerName, config, opened)
+	if err != nil {
+		log.Printf("open provider fail. provider=%s, err='%s'", providerName, err)
+		return
+	}
+	var checker source.Provider
+	if crossCheck != "" {
+		if checker, err = source.OpenFailover(crossCheck, config, opened); err != nil {
+			log.Printf("open cross-check provider fail. provider=%s, err='%s'", crossCheck, err)
+			return
+		}
+	}
+	var all []*cninfo.Stock
 	if w1 {
-		stocks, err := source.GetStockList()
-		if err != nil {
+		if all, err = getStocks(ctx, cninfoSource, markets); err != nil {
 			log.Printf("get stock fail. err='%s'", err)
 			return
 		}
-		err = writeStocks("stock.txt", stocks)
-		if err != nil {
+		if err := writeStocks("stock.txt", all); err != nil {
 			log.Printf("write stock fail. err='%s'", err)
 			return
 		}
-		log.Printf("write stock success. count=%d", len(stocks))
+		log.Printf("write stock success. count=%d", len(all))
 	}
 
-	if !w2 && !w3 {
+	if !w2 && !w3 && !w4 {
 		return
 	}
 
@@ -65,59 +128,79 @@ func main() {
 		log.Printf("no watch stock.")
 		return
 	}
+	if stocks, err = resolveStocks(ctx, cninfoSource, markets, all, stocks); err != nil {
+		log.Printf("resolve watch stock fail. err='%s'", err)
+		return
+	}
+	if stocks = filterPlates(stocks, plates); len(stocks) == 0 {
+		log.Printf("no watch stock of plates. plates=%s", plateNames)
+		return
+	}
 
+	bulkOptions := &cninfo.BulkOptions{Workers: workers}
 	if w2 {
-		for _, stock := range stocks {
-			diff := false
-			start, end := time.Date(2000, 1, 1, 0, 0, 0, 0, time.Local), time.Now()
-			announcements, err := readStockReportAnnouncements(stock)
-			if err != nil {
-				log.Printf("read stock report announcements error. code=%s, err='%v'", stock.Code, err)
-				continue
-			}
-			if len(announcements) > 0 {
-				start = end.AddDate(-3, 0, 0)
-				diff = true
+		polled := stocks
+		var rs *reportSync
+		if syncReport {
+			if rs, err = syncReportAnnouncements(ctx, cninfoSource, "sync.txt", stocks, plates, trades); err != nil {
+				log.Printf("sync report announcements fail. err='%s'", err)
+				return
 			}
-			_announcements, err := source.GetAnnualReportAnnoucements(stock, start, end)
-			if err != nil {
-				log.Printf("get stock report announcements error. code=%s, err='%v'", stock.Code, err)
-				continue
-			}
-			var __announcements []*cninfo.Announcement
-			for _, _announcement := range _announcements {
-				has := false
-				for _, announcement := range announcements {
-					if announcement.AnnouncementID == _announcement.AnnouncementID {
-						has = true
-						break
-					}
+			if len(trades) > 0 {
+				if err := rs.checkTradeMembers(ctx, cninfoSource, "sync-trades.txt", stocks, trades); err != nil {
+					log.Printf("check watch stock trades fail. err='%s'", err)
+					return
 				}
-				if !has {
-					announcements = append(announcements, _announcement)
-					__announcements = append(__announcements, _announcement)
+				for _, stock := range stocks {
+					if (stock.Market == "" || stock.Market == cninfo.MarketAShare) && !rs.members[stock.Code] {
+						log.Printf("watch stock not of trades, query report announcements alone. code=%s, trades=%s", stock.Code, tradeNames)
+					}
 				}
 			}
-			if len(__announcements) == 0 {
-				continue
+			polled = nil
+			for _, stock := range stocks {
+				update, err := rs.update(stock)
+				if err != nil {
+					log.Printf("get stock report announcements error. code=%s, err='%v'", stock.Code, err)
+					continue
+				}
+				if update == nil {
+					polled = append(polled, stock)
+					continue
+				}
+				applyStockReportUpdate(stock, update)
 			}
-			sortStockReportAnnouncements(announcements)
-			if err = writeStockReportAnnouncements(stock, announcements); err != nil {
-				log.Printf("write stock report announcements error. code=%s, err='%v'", stock.Code, err)
+		}
+		failed := 0
+		for r := range cninfo.Bulk(ctx, polled, bulkOptions, func(ctx context.Context, stock *cninfo.Stock) (*reportUpdate, error) {
+			return getStockReportUpdate(ctx, provider, checker, stock)
+		}) {
+			if r.Err != nil {
+				failed++
+				log.Printf("get stock report announcements error. code=%s, err='%v'", r.Stock.Code, r.Err)
 				continue
 			}
-			if diff {
-				sortStockReportAnnouncements(__announcements)
-				sendStockReportAnnouncementsNotification(stock, __announcements)
+			applyStockReportUpdate(r.Stock, r.Value)
+		}
+		if ctx.Err() != nil {
+			log.Printf("watch stock report announcements interrupted. err='%v'", ctx.Err())
+			return
+		}
+		// New cursors are only worth keeping once every stock is up to
+		// date, otherwise the failed ones would miss announcements.
+		if rs != nil && (failed == 0 || !rs.started) {
+			if err := writeSyncCursors("sync.txt", rs.cursors); err != nil {
+				log.Printf("write sync cursor fail. err='%s'", err)
+				return
 			}
 		}
 	}
 
 	if w3 {
-		for _, stock := range stocks {
-			records, err := source.GetDividendRecords(stock)
-			if err != nil {
-				log.Printf("get stock dividend records error. code=%s, err='%v'", stock.Code, err)
+		for r := range cninfo.Bulk(ctx, stocks, bulkOptions, provider.GetDividendRecordsContext) {
+			stock, records := r.Stock, r.Value
+			if r.Err != nil {
+				log.Printf("get stock dividend records error. code=%s, err='%v'", stock.Code, r.Err)
 				continue
 			}
 			err = writeStockDividendRecords(stock, records)
@@ -127,6 +210,38 @@ func main() {
 			}
 			sendStockDividendRecordsNotification(stock, records)
 		}
+		if ctx.Err() != nil {
+			log.Printf("watch stock dividend records interrupted. err='%v'", ctx.Err())
+			return
+		}
+	}
+
+	if w4 {
+		for _, stock := range stocks {
+			if ctx.Err() != nil {
+				log.Printf("watch stock shareholders interrupted. err='%v'", ctx.Err())
+				return
+			}
+			topTen, err := cninfoSource.GetTopTenShareholdersContext(ctx, stock)
+			if err != nil {
+				log.Printf("get stock top ten shareholders error. code=%s, err='%v'", stock.Code, err)
+				continue
+			}
+			tradable, err := cninfoSource.GetTopTenTradableShareholdersContext(ctx, stock)
+			if err != nil {
+				log.Printf("get stock top ten tradable shareholders error. code=%s, err='%v'", stock.Code, err)
+				continue
+			}
+			counts, err := cninfoSource.GetShareholderCountsContext(ctx, stock)
+			if err != nil {
+				log.Printf("get stock shareholder counts error. code=%s, err='%v'", stock.Code, err)
+				continue
+			}
+			if err := writeStockShareholders(stock, topTen, tradable, counts); err != nil {
+				log.Printf("write stock shareholders error. code=%s, err='%v'", stock.Code, err)
+				continue
+			}
+		}
 	}
 }
 
@@ -146,7 +261,12 @@ func sendStockDividendRecordsNotification(stock *cninfo.Stock, records []*cninfo
 		return
 	}
 	latest := records[0]
-	if latest.PayDate != time.Now().Format("2006-01-02") {
+	payDate, err := latest.PayTime()
+	if err != nil {
+		log.Printf("send stock dividend records notification skip. err='%s'", err)
+		return
+	}
+	if payDate.IsZero() || !payDate.Equal(cninfo.Today()) {
 		return
 	}
 
@@ -273,7 +393,11 @@ func writeStockDividendRecords(stock *cninfo.Stock, records []*cninfo.DividendRe
 		if record.PayDate == "" {
 			payDate = "--"
 		}
-		if _, err := buf.WriteString(fmt.Sprintf("%s,%s,%s,%s,%s\n", period, record.Plan, record.RecordDate, record.ExDividendDate, payDate)); err != nil {
+		line := fmt.Sprintf("%s,%s,%s,%s,%s", period, record.Plan, record.RecordDate, record.ExDividendDate, payDate)
+		if parsePlan {
+			line += "," + planFields(stock, record)
+		}
+		if _, err := buf.WriteString(line + "\n"); err != nil {
 			log.Printf("write stock dividend records fail. code=%s, err='%s'", stock.Code, err)
 			return err
 		}
@@ -288,19 +412,139 @@ func writeStockDividendRecords(stock *cninfo.Stock, records []*cninfo.DividendRe
 	return nil
 }
 
+func planFields(stock *cninfo.Stock, record *cninfo.DividendRecord) string {
+	plan, err := record.ParsePlan()
+	if err != nil {
+		log.Printf("parse stock dividend plan fail. code=%s, err='%s'", stock.Code, err)
+		return ",,,,"
+	}
+	return fmt.Sprintf("%.6g,%.6g,%.6g,%.6g,%s", plan.CashPerShare(), plan.CashAfterTaxPerShare(), plan.BonusPerShare(), plan.TransferPerShare(), plan.Currency)
+}
+
+// writeStockShareholders writes one line per reporting period, latest first:
+// period, top ten ratio, top ten tradable ratio, shareholder count and the
+// change of each against the previous period. A change of the latest period
+// is logged as well.
+func writeStockShareholders(stock *cninfo.Stock, topTen, tradable []*cninfo.ShareholderPeriod, counts []*cninfo.ShareholderCount) error {
+	type Row struct {
+		TopTen   float64
+		Tradable float64
+		Count    float64
+	}
+	rows := make(map[string]*Row)
+	row := func(period string) *Row {
+		r := rows[period]
+		if r == nil {
+			r = &Row{}
+			rows[period] = r
+		}
+		return r
+	}
+	for _, p := range topTen {
+		row(p.EndDate).TopTen = p.Ratio()
+	}
+	for _, p := range tradable {
+		row(p.EndDate).Tradable = p.Ratio()
+	}
+	for _, c := range counts {
+		row(c.EndDate).Count = c.Count
+	}
+	if len(rows) == 0 {
+		log.Printf("write stock shareholders skip. no shareholder. code=%s", stock.Code)
+		return nil
+	}
+	var periods []string
+	for period := range rows {
+		periods = append(periods, period)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(periods)))
+
+	var buf bytes.Buffer
+	for i, period := range periods {
+		r, prev := rows[period], &Row{}
+		if i+1 < len(periods) {
+			prev = rows[periods[i+1]]
+		}
+		countChange := 0.0
+		if prev.Count > 0 {
+			countChange = (r.Count - prev.Count) / prev.Count * 100
+		}
+		if _, err := buf.WriteString(fmt.Sprintf("%s,%.2f,%+.2f,%.2f,%+.2f,%.0f,%+.2f%%\n", period, r.TopTen, r.TopTen-prev.TopTen, r.Tradable, r.Tradable-prev.Tradable, r.Count, countChange)); err != nil {
+			return err
+		}
+		if i == 0 && i+1 < len(periods) {
+			log.Printf("stock shareholder concentration. code=%s, period=%s, top ten=%.2f%%(%+.2f), top ten tradable=%.2f%%(%+.2f), count=%.0f(%+.2f%%)", stock.Code, period, r.TopTen, r.TopTen-prev.TopTen, r.Tradable, r.Tradable-prev.Tradable, r.Count, countChange)
+		}
+	}
+	os.MkdirAll("shareholder", 0755)
+	return os.WriteFile(fmt.Sprintf("shareholder/%s.txt", stock.Code), buf.Bytes(), 0644)
+}
+
 func writeStocks(name string, stocks []*cninfo.Stock) error {
 	if len(stocks) == 0 {
 		return fmt.Errorf("no stock")
 	}
 	var buf bytes.Buffer
 	for _, stock := range stocks {
-		if _, err := buf.WriteString(fmt.Sprintf("%s,%s,%s,%s,%s\n", stock.Code, stock.Zwjc, stock.Pinyin, stock.Category, stock.OrgID)); err != nil {
+		if _, err := buf.WriteString(fmt.Sprintf("%s,%s,%s,%s,%s,%s\n", stock.Code, stock.Zwjc, stock.Pinyin, stock.Category, stock.OrgID, stock.Market)); err != nil {
 			return err
 		}
 	}
 	return os.WriteFile(name, buf.Bytes(), 0644)
 }
 
+func getStocks(ctx context.Context, s *cninfo.Source, markets string) ([]*cninfo.Stock, error) {
+	var stocks []*cninfo.Stock
+	for _, name := range strings.Split(markets, ",") {
+		market, err := cninfo.ParseMarket(name)
+		if err != nil {
+			return nil, err
+		}
+		_stocks, err := s.GetMarketStockListContext(ctx, market)
+		if err != nil {
+			return nil, fmt.Errorf("market %s: %w", name, err)
+		}
+		stocks = append(stocks, _stocks...)
+	}
+	return stocks, nil
+}
+
+// resolveStocks replaces the watch stocks given by a code, pinyin or name
+// alone with the stocks they refer to in all, stock.txt or the stock list
+// of markets, in that order.
+func resolveStocks(ctx context.Context, s *cninfo.Source, markets string, all, stocks []*cninfo.Stock) ([]*cninfo.Stock, error) {
+	var index *cninfo.StockIndex
+	var resolved []*cninfo.Stock
+	var err error
+	seen := make(map[string]bool)
+	for _, stock := range stocks {
+		if stock.OrgID == "" && stock.Zwjc == "" {
+			if index == nil {
+				if len(all) == 0 {
+					if all, err = readStocks("stock.txt"); err != nil {
+						return nil, err
+					}
+				}
+				if len(all) == 0 {
+					if all, err = getStocks(ctx, s, markets); err != nil {
+						return nil, err
+					}
+				}
+				index = cninfo.NewStockIndex(all)
+			}
+			if stock, err = index.Resolve(stock.Code); err != nil {
+				return nil, err
+			}
+		}
+		if seen[stock.Code] {
+			continue
+		}
+		seen[stock.Code] = true
+		resolved = append(resolved, stock)
+	}
+	return resolved, nil
+}
+
 func readStocks(name string) ([]*cninfo.Stock, error) {
 	b, err := os.ReadFile(name)
 	if err != nil {
@@ -311,20 +555,30 @@ func readStocks(name string) ([]*cninfo.Stock, error) {
 	}
 	var stocks []*cninfo.Stock
 	for _, line := range strings.Split(string(b), "\n") {
-		if line == "" {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
 			continue
 		}
 		f := strings.Split(line, ",")
-		if len(f) != 5 {
+		if len(f) == 1 {
+			stocks = append(stocks, &cninfo.Stock{Code: strings.TrimSpace(f[0])})
+			continue
+		}
+		if len(f) != 5 && len(f) != 6 {
 			return nil, fmt.Errorf("invalid line")
 		}
-		stocks = append(stocks, &cninfo.Stock{
+		stock := &cninfo.Stock{
 			Code:     f[0],
 			Zwjc:     f[1],
 			Pinyin:   f[2],
 			Category: f[3],
 			OrgID:    f[4],
-		})
+			Market:   cninfo.MarketAShare,
+		}
+		if len(f) == 6 && f[5] != "" {
+			stock.Market = cninfo.Market(f[5])
+		}
+		stocks = append(stocks, stock)
 	}
 	return stocks, nil
 }
@@ -343,6 +597,366 @@ func writeStockReportAnnouncements(stock *cninfo.Stock, announcements []*cninfo.
 	return os.WriteFile(fmt.Sprintf("report/%s.txt", stock.Code), buf.Bytes(), 0644)
 }
 
+func applyStockReportUpdate(stock *cninfo.Stock, update *reportUpdate) {
+	if len(update.added) == 0 {
+		return
+	}
+	sortStockReportAnnouncements(update.announcements)
+	if err := writeStockReportAnnouncements(stock, update.announcements); err != nil {
+		log.Printf("write stock report announcements error. code=%s, err='%v'", stock.Code, err)
+		return
+	}
+	if update.diff {
+		sortStockReportAnnouncements(update.added)
+		sendStockReportAnnouncementsNotification(stock, update.added)
+	}
+}
+
+type reportUpdate struct {
+	announcements []*cninfo.Announcement
+	added         []*cninfo.Announcement
+	diff          bool
+}
+
+// getStockReportUpdate merges the annual report announcements of stock
+// with the ones written before. Only the latest three years are queried
+// once there are some.
+func getStockReportUpdate(ctx context.Context, provider, checker source.Provider, stock *cninfo.Stock) (*reportUpdate, error) {
+	update := &reportUpdate{}
+	start, end := time.Date(2000, 1, 1, 0, 0, 0, 0, cninfo.Location), cninfo.Now()
+	announcements, err := readStockReportAnnouncements(stock)
+	if err != nil {
+		return nil, fmt.Errorf("read announcements: %w", err)
+	}
+	if len(announcements) > 0 {
+		start = end.AddDate(-3, 0, 0)
+		update.diff = true
+	}
+	_announcements, err := provider.GetPeriodicReportAnnouncementsContext(ctx, stock, cninfo.CategoryAnnualReport, start, end)
+	if err != nil {
+		return nil, err
+	}
+	if checker != nil {
+		crossCheckStockReportAnnouncements(ctx, checker, stock, start, end, _announcements)
+	}
+	mergeStockReportUpdate(update, announcements, _announcements)
+	return update, nil
+}
+
+// mergeStockReportUpdate sets update to announcements with the ones of
+// _announcements not in there yet.
+func mergeStockReportUpdate(update *reportUpdate, announcements, _announcements []*cninfo.Announcement) {
+	for _, _announcement := range _announcements {
+		has := false
+		for _, announcement := range announcements {
+			if announcement.AnnouncementID == _announcement.AnnouncementID {
+				has = true
+				break
+			}
+		}
+		if !has {
+			announcements = append(announcements, _announcement)
+			update.added = append(update.added, _announcement)
+		}
+	}
+	update.announcements = announcements
+}
+
+// reportSync is the annual report announcements of the markets of the
+// watch stocks synced by day.
+type reportSync struct {
+	cursors []*cninfo.SyncCursor
+	synced  map[cninfo.Market]bool
+	codes   map[string][]*cninfo.Announcement
+	orgs    map[string][]*cninfo.Announcement
+
+	// members are the A-share stocks of the trades of the sync, if any.
+	// The others are left out of the sync and have to be queried alone.
+	members map[string]bool
+
+	// started is set when some cursors are new.
+	started bool
+}
+
+// filterPlates returns the stocks on any of plates, or all of them if no
+// plates are given.
+func filterPlates(stocks []*cninfo.Stock, plates []cninfo.Plate) []*cninfo.Stock {
+	if len(plates) == 0 {
+		return stocks
+	}
+	var filtered []*cninfo.Stock
+	for _, stock := range stocks {
+		for _, plate := range plates {
+			if plate.Contains(stock.Code) && (stock.Market == "" || stock.Market == cninfo.MarketAShare) {
+				filtered = append(filtered, stock)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// syncReportAnnouncements syncs the annual report announcements of the
+// markets of stocks, restricted to plates and trades, from the cursors in
+// name. Markets without a cursor get one starting today, their stocks have
+// to be queried one by one this time.
+func syncReportAnnouncements(ctx context.Context, s *cninfo.Source, name string, stocks []*cninfo.Stock, plates []cninfo.Plate, trades []cninfo.Trade) (*reportSync, error) {
+	cursors, err := readSyncCursors(name)
+	if err != nil {
+		return nil, err
+	}
+	rs := &reportSync{
+		cursors: cursors,
+		synced:  make(map[cninfo.Market]bool),
+		codes:   make(map[string][]*cninfo.Announcement),
+		orgs:    make(map[string][]*cninfo.Announcement),
+	}
+	now := cninfo.Now()
+	for _, stock := range stocks {
+		market := stock.Market
+		if market == "" {
+			market = cninfo.MarketAShare
+		}
+		if _, ok := rs.synced[market]; ok {
+			continue
+		}
+		var cursor *cninfo.SyncCursor
+		p, t := plates, trades
+		if market != cninfo.MarketAShare {
+			p, t = nil, nil
+		}
+		for _, c := range cursors {
+			if c.Matches(market, cninfo.CategoryAnnualReport, p, t) {
+				cursor = c
+				break
+			}
+		}
+		if cursor == nil {
+			cursor = cninfo.NewSyncCursor(market, cninfo.CategoryAnnualReport, now)
+			if market == cninfo.MarketAShare {
+				cursor.Plates, cursor.Trades = plates, trades
+			}
+			rs.cursors = append(rs.cursors, cursor)
+			rs.synced[market] = false
+			rs.started = true
+			continue
+		}
+		announcements, err := s.Sync(ctx, cursor, now)
+		if err != nil {
+			return nil, fmt.Errorf("market %s: %w", market, err)
+		}
+		for code, announcements := range cninfo.GroupAnnouncements(announcements) {
+			rs.codes[code] = announcements
+			rs.orgs[announcements[0].OrgID] = announcements
+		}
+		rs.synced[market] = true
+	}
+	return rs, nil
+}
+
+// update returns the report update of stock from the synced announcements,
+// or nil if stock has to be queried alone because its market is not synced
+// yet or it has no announcements written before.
+func (rs *reportSync) update(stock *cninfo.Stock) (*reportUpdate, error) {
+	market := stock.Market
+	if market == "" {
+		market = cninfo.MarketAShare
+	}
+	if !rs.synced[market] {
+		return nil, nil
+	}
+	if rs.members != nil && market == cninfo.MarketAShare && !rs.members[stock.Code] {
+		return nil, nil
+	}
+	announcements, err := readStockReportAnnouncements(stock)
+	if err != nil {
+		return nil, fmt.Errorf("read announcements: %w", err)
+	}
+	if len(announcements) == 0 {
+		return nil, nil
+	}
+	_announcements, ok := rs.codes[stock.Code]
+	if !ok {
+		if stock.OrgID != "" {
+			_announcements = rs.orgs[stock.OrgID]
+		}
+	}
+	update := &reportUpdate{diff: true}
+	mergeStockReportUpdate(update, announcements, _announcements)
+	return update, nil
+}
+
+// checkTradeMembers sets the members of rs to the A-share stocks of trades,
+// as written to name before. Stocks not checked yet are checked by whether
+// their annual reports of the last three years are found with the trades
+// filter, new listings without any are taken for not of the trades.
+func (rs *reportSync) checkTradeMembers(ctx context.Context, s *cninfo.Source, name string, stocks []*cninfo.Stock, trades []cninfo.Trade) error {
+	checked, err := readTradeMembers(name)
+	if err != nil {
+		return err
+	}
+	key := joinTrades(trades)
+	if checked[key] == nil {
+		checked[key] = make(map[string]bool)
+	}
+	members := checked[key]
+	end := cninfo.Now()
+	changed := false
+	for _, stock := range stocks {
+		if stock.Market != "" && stock.Market != cninfo.MarketAShare {
+			continue
+		}
+		if _, ok := members[stock.Code]; ok {
+			continue
+		}
+		q := &cninfo.SearchQuery{Market: cninfo.MarketAShare, Stock: stock, Category: cninfo.CategoryAnnualReport, Trades: trades}
+		announcements, err := s.GetAnnouncementsContext(ctx, q, end.AddDate(-3, 0, 0), end)
+		if err != nil {
+			return fmt.Errorf("stock %s: %w", stock.Code, err)
+		}
+		members[stock.Code] = len(announcements) > 0
+		changed = true
+	}
+	if changed {
+		if err := writeTradeMembers(name, checked); err != nil {
+			return err
+		}
+	}
+	rs.members = members
+	return nil
+}
+
+func joinTrades(trades []cninfo.Trade) string {
+	var s []string
+	for _, trade := range trades {
+		s = append(s, string(trade))
+	}
+	return strings.Join(s, ";")
+}
+
+// readTradeMembers reads the stocks checked against trades, by the trades
+// joined by semicolon and then by code.
+func readTradeMembers(name string) (map[string]map[string]bool, error) {
+	checked := make(map[string]map[string]bool)
+	b, err := os.ReadFile(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return checked, nil
+		}
+		return nil, err
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		if line == "" {
+			continue
+		}
+		f := strings.Split(line, ",")
+		if len(f) != 3 {
+			return nil, fmt.Errorf("invalid line")
+		}
+		if checked[f[2]] == nil {
+			checked[f[2]] = make(map[string]bool)
+		}
+		checked[f[2]][f[0]] = f[1] == "1"
+	}
+	return checked, nil
+}
+
+func writeTradeMembers(name string, checked map[string]map[string]bool) error {
+	var keys []string
+	for key := range checked {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var buf bytes.Buffer
+	for _, key := range keys {
+		var codes []string
+		for code := range checked[key] {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		for _, code := range codes {
+			member := "0"
+			if checked[key][code] {
+				member = "1"
+			}
+			if _, err := buf.WriteString(fmt.Sprintf("%s,%s,%s\n", code, member, key)); err != nil {
+				return err
+			}
+		}
+	}
+	return os.WriteFile(name, buf.Bytes(), 0644)
+}
+
+func readSyncCursors(name string) ([]*cninfo.SyncCursor, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var cursors []*cninfo.SyncCursor
+	for _, line := range strings.Split(string(b), "\n") {
+		if line == "" {
+			continue
+		}
+		f := strings.Split(line, ",")
+		if len(f) != 3 && len(f) != 5 {
+			return nil, fmt.Errorf("invalid line")
+		}
+		cursor := &cninfo.SyncCursor{Market: cninfo.Market(f[0]), Category: cninfo.Category(f[1]), Date: f[2]}
+		if len(f) == 5 {
+			for _, plate := range strings.Split(f[3], ";") {
+				if plate != "" {
+					cursor.Plates = append(cursor.Plates, cninfo.Plate(plate))
+				}
+			}
+			for _, trade := range strings.Split(f[4], ";") {
+				if trade != "" {
+					cursor.Trades = append(cursor.Trades, cninfo.Trade(trade))
+				}
+			}
+		}
+		cursors = append(cursors, cursor)
+	}
+	return cursors, nil
+}
+
+func writeSyncCursors(name string, cursors []*cninfo.SyncCursor) error {
+	var buf bytes.Buffer
+	for _, cursor := range cursors {
+		var plates, trades []string
+		for _, plate := range cursor.Plates {
+			plates = append(plates, string(plate))
+		}
+		for _, trade := range cursor.Trades {
+			trades = append(trades, string(trade))
+		}
+		if _, err := buf.WriteString(fmt.Sprintf("%s,%s,%s,%s,%s\n", cursor.Market, cursor.Category, cursor.Date, strings.Join(plates, ";"), strings.Join(trades, ";"))); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(name, buf.Bytes(), 0644)
+}
+
+func crossCheckStockReportAnnouncements(ctx context.Context, checker source.Provider, stock *cninfo.Stock, start, end time.Time, announcements []*cninfo.Announcement) {
+	_announcements, err := checker.GetPeriodicReportAnnouncementsContext(ctx, stock, cninfo.CategoryAnnualReport, start, end)
+	if errors.Is(err, source.ErrNotSupported) {
+		return
+	}
+	if err != nil {
+		log.Printf("cross-check stock report announcements error. code=%s, err='%v'", stock.Code, err)
+		return
+	}
+	missing, extra := source.Compare(_announcements, announcements)
+	for _, announcement := range missing {
+		log.Printf("cross-check stock report announcement missing. code=%s, date=%s, title=%s", stock.Code, announcement.Time().Format("2006-01-02"), announcement.AnnouncementTitle)
+	}
+	for _, announcement := range extra {
+		log.Printf("cross-check stock report announcement unconfirmed. code=%s, date=%s, title=%s", stock.Code, announcement.Time().Format("2006-01-02"), announcement.AnnouncementTitle)
+	}
+}
+
 func readStockReportAnnouncements(stock *cninfo.Stock) ([]*cninfo.Announcement, error) {
 	b, err := os.ReadFile(fmt.Sprintf("report/%s.txt", stock.Code))
 	if err != nil {
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chamzzzzzz/financial/source/cninfo"
+	"github.com/chamzzzzzz/financial/source/cninfo/cninfotest"
+	"github.com/chamzzzzzz/financial/source/cninfo/replay/replaytest"
+)
+
+func chdir(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestWriteReadStocks(t *testing.T) {
+	stocks, err := replaytest.NewSource(t, filepath.Join("..", "..", "source", "cninfo", "testdata", "stock_list.json")).GetStockList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Join(t.TempDir(), "stock.txt")
+	if err := writeStocks(name, stocks); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readStocks(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(stocks) {
+		t.Fatalf("got %d stocks, want %d", len(got), len(stocks))
+	}
+	for i := range got {
+		if *got[i] != *stocks[i] {
+			t.Errorf("got stock %+v, want %+v", got[i], stocks[i])
+		}
+	}
+}
+
+func TestWriteStockDividendRecords(t *testing.T) {
+	stock := &cninfo.Stock{Code: "000001"}
+	records, err := replaytest.NewSource(t, filepath.Join("..", "..", "source", "cninfo", "testdata", "dividend.json")).GetDividendRecords(stock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chdir(t)
+	parsePlan = true
+	defer func() { parsePlan = false }()
+	if err := writeStockDividendRecords(stock, records); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile("dividend/000001.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	want := []string{
+		"2022年报,10派2.85元(含税),2023-06-13,2023-06-14,2023-06-14,0.285,0,0,0,CNY",
+		"2021年报,10派2.28元(含税,扣税后2.052元),2022-07-21,2022-07-22,2022-07-22,0.228,0.2052,0,0,CNY",
+		"2020年报,10派1.8元(含税),2021-05-13,2021-05-14,2021-05-14,0.18,0,0,0,CNY",
+		"2016年报,10送2股派1.58元(含税),2017-07-20,2017-07-21,--,0.158,0,0.2,0,CNY",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d", len(lines), len(want))
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("got line %q, want %q", lines[i], want[i])
+		}
+	}
+}
+
+func TestResolveStocks(t *testing.T) {
+	s := replaytest.NewSource(t, filepath.Join("..", "..", "source", "cninfo", "testdata", "stock_list.json"))
+	chdir(t)
+	if err := os.WriteFile("watch.txt", []byte("pfyh\n平安银行\r\n000001\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stocks, err := readStocks("watch.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stocks, err = resolveStocks(context.Background(), s, "a-share", nil, stocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stocks) != 2 || stocks[0].Code != "600000" || stocks[1].Code != "000001" || stocks[1].OrgID != "gssz0000001" {
+		t.Fatalf("got %+v, want 600000 and 000001", stocks)
+	}
+}
+
+func TestReportSyncTradeMembers(t *testing.T) {
+	chdir(t)
+	server := cninfotest.NewServer(&cninfotest.Dataset{
+		Announcements: map[cninfo.Category][]*cninfo.Announcement{
+			cninfo.CategoryAnnualReport: {
+				{AnnouncementID: "1", SecCode: "000001", OrgID: "gssz0000001", AnnouncementTitle: "年度报告", AnnouncementTime: cninfo.Now().AddDate(-1, 0, 0).UnixMilli()},
+				{AnnouncementID: "2", SecCode: "000002", OrgID: "gssz0000002", AnnouncementTitle: "年度报告", AnnouncementTime: cninfo.Now().AddDate(-1, 0, 0).UnixMilli()},
+			},
+		},
+		Trades: map[string]cninfo.Trade{"000001": cninfo.TradeFinance, "000002": cninfo.TradeRealEstate},
+	})
+	defer server.Close()
+	stocks := []*cninfo.Stock{{Code: "000001", OrgID: "gssz0000001"}, {Code: "000002", OrgID: "gssz0000002"}}
+	for _, stock := range stocks {
+		if err := writeStockReportAnnouncements(stock, []*cninfo.Announcement{{AnnouncementID: "0", SecCode: stock.Code, AnnouncementTitle: "年度报告"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	trades := []cninfo.Trade{cninfo.TradeFinance}
+
+	rs := &reportSync{synced: map[cninfo.Market]bool{cninfo.MarketAShare: true}}
+	if err := rs.checkTradeMembers(context.Background(), server.Source(), "sync-trades.txt", stocks, trades); err != nil {
+		t.Fatal(err)
+	}
+	if !rs.members["000001"] || rs.members["000002"] {
+		t.Fatalf("got members %v, want 000001 only", rs.members)
+	}
+	if update, err := rs.update(stocks[0]); err != nil || update == nil {
+		t.Fatalf("got %v, %v, want 000001 updated from the sync", update, err)
+	}
+	if update, err := rs.update(stocks[1]); err != nil || update != nil {
+		t.Fatalf("got %v, %v, want 000002 queried alone", update, err)
+	}
+
+	requests := server.Requests()
+	rs = &reportSync{}
+	if err := rs.checkTradeMembers(context.Background(), server.Source(), "sync-trades.txt", stocks, trades); err != nil {
+		t.Fatal(err)
+	}
+	if server.Requests() != requests || !rs.members["000001"] || rs.members["000002"] {
+		t.Fatalf("got members %v after %d requests, want the checked ones read back", rs.members, server.Requests()-requests)
+	}
+}
+
+func TestWriteStockShareholders(t *testing.T) {
+	s := replaytest.NewSource(t, filepath.Join("..", "..", "source", "cninfo", "testdata", "shareholder.json"))
+	stock := &cninfo.Stock{Code: "000001"}
+	topTen, err := s.GetTopTenShareholders(stock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tradable, err := s.GetTopTenTradableShareholders(stock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts, err := s.GetShareholderCounts(stock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chdir(t)
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	if err := writeStockShareholders(stock, topTen, tradable, counts); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile("shareholder/000001.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "2023-03-31,56.29,-0.52,49.56,+0.00,525000,+5.00%\n" +
+		"2022-12-31,56.81,+56.81,49.56,+49.56,500000,+0.00%\n"
+	if string(b) != want {
+		t.Fatalf("got\n%swant\n%s", b, want)
+	}
+	if !strings.Contains(logs.String(), "period=2023-03-31, top ten=56.29%(-0.52), top ten tradable=49.56%(+0.00), count=525000(+5.00%)") {
+		t.Fatalf("got log %q, want the change of the latest period", logs.String())
+	}
+}
@@ -0,0 +1,86 @@
+package cninfo
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultBulkWorkers is the number of concurrent requests of a bulk query
+// without BulkOptions.
+const DefaultBulkWorkers = 4
+
+// BulkOptions configures a bulk query.
+type BulkOptions struct {
+	// Workers is the number of requests run concurrently. If not positive,
+	// DefaultBulkWorkers is used. The requests still share the rate limit
+	// of the source.
+	Workers int
+}
+
+func (o *BulkOptions) workers() int {
+	if o == nil || o.Workers <= 0 {
+		return DefaultBulkWorkers
+	}
+	return o.Workers
+}
+
+// BulkResult is the result of a bulk query for one stock. Err is the error
+// of this stock only, the other stocks are still queried.
+type BulkResult[T any] struct {
+	Stock *Stock
+	Value T
+	Err   error
+}
+
+// Bulk calls fn for every stock on a bounded pool of workers and streams
+// the results as they complete. The channel is closed when every stock is
+// done, or early when ctx is done, in which case the remaining stocks are
+// skipped. Callers must drain the channel or cancel ctx.
+func Bulk[T any](ctx context.Context, stocks []*Stock, opts *BulkOptions, fn func(ctx context.Context, stock *Stock) (T, error)) <-chan *BulkResult[T] {
+	jobs := make(chan *Stock)
+	results := make(chan *BulkResult[T])
+	var wg sync.WaitGroup
+	for i := 0; i < opts.workers(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for stock := range jobs {
+				value, err := fn(ctx, stock)
+				select {
+				case results <- &BulkResult[T]{Stock: stock, Value: value, Err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for _, stock := range stocks {
+			select {
+			case jobs <- stock:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
+
+// BulkGetDividendRecords gets the dividend records of stocks concurrently.
+func (s *Source) BulkGetDividendRecords(ctx context.Context, stocks []*Stock, opts *BulkOptions) <-chan *BulkResult[[]*DividendRecord] {
+	return Bulk(ctx, stocks, opts, s.GetDividendRecordsContext)
+}
+
+// BulkGetPeriodicReportAnnouncements gets the periodic report announcements
+// of category of stocks concurrently.
+func (s *Source) BulkGetPeriodicReportAnnouncements(ctx context.Context, stocks []*Stock, category Category, start, end time.Time, opts *BulkOptions) <-chan *BulkResult[[]*Announcement] {
+	return Bulk(ctx, stocks, opts, func(ctx context.Context, stock *Stock) ([]*Announcement, error) {
+		return s.GetPeriodicReportAnnouncementsContext(ctx, stock, category, start, end)
+	})
+}
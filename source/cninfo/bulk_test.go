@@ -0,0 +1,60 @@
+package cninfo_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/chamzzzzzz/financial/source/cninfo"
+	"github.com/chamzzzzzz/financial/source/cninfo/cninfotest"
+)
+
+func TestBulkGetDividendRecords(t *testing.T) {
+	dataset := &cninfotest.Dataset{Dividends: make(map[string][]*cninfo.DividendRecord)}
+	var stocks []*cninfo.Stock
+	for i := 1; i <= 20; i++ {
+		code := fmt.Sprintf("%06d", i)
+		stocks = append(stocks, &cninfo.Stock{Code: code})
+		dataset.Dividends[code] = []*cninfo.DividendRecord{{Period: "2022年报", Plan: code}}
+	}
+	server := cninfotest.NewServer(dataset)
+	defer server.Close()
+	server.Fail(1, cninfotest.Fault{Malformed: true})
+
+	seen := make(map[string]bool)
+	var failed int
+	for r := range server.Source().BulkGetDividendRecords(context.Background(), stocks, &cninfo.BulkOptions{Workers: 5}) {
+		if seen[r.Stock.Code] {
+			t.Fatalf("got stock %s twice", r.Stock.Code)
+		}
+		seen[r.Stock.Code] = true
+		if r.Err != nil {
+			failed++
+			continue
+		}
+		if len(r.Value) != 1 || r.Value[0].Plan != r.Stock.Code {
+			t.Errorf("got records %+v of stock %s", r.Value, r.Stock.Code)
+		}
+	}
+	if len(seen) != len(stocks) || failed != 1 {
+		t.Fatalf("got %d stocks and %d failed, want %d and 1", len(seen), failed, len(stocks))
+	}
+}
+
+func TestBulkCancel(t *testing.T) {
+	stocks := make([]*cninfo.Stock, 100)
+	for i := range stocks {
+		stocks[i] = &cninfo.Stock{Code: fmt.Sprintf("%06d", i)}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	n := 0
+	for range cninfo.Bulk(ctx, stocks, nil, func(ctx context.Context, stock *cninfo.Stock) (int, error) { return 0, nil }) {
+		if n++; n == 10 {
+			cancel()
+		}
+	}
+	if n >= len(stocks) {
+		t.Fatalf("got %d results after cancel, want fewer than %d", n, len(stocks))
+	}
+}
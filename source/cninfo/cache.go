@@ -0,0 +1,137 @@
+package cninfo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultCacheTTLs are the cache lifetimes by endpoint path prefix used when
+// Cache.TTLs is nil.
+var DefaultCacheTTLs = map[string]time.Duration{
+	"/new/data/":                 24 * time.Hour,
+	"/new/hisAnnouncement/query": time.Hour,
+	"/data20/companyOverview/":   24 * time.Hour,
+	"/data20/shareholders/":      24 * time.Hour,
+	"/data20/financialData/":     24 * time.Hour,
+}
+
+// Cache is an on-disk response cache for Source. Responses are keyed by
+// method, URL and form body. Within its TTL a response is served without
+// touching the network; after that it is revalidated with ETag and
+// Last-Modified when cninfo sent them.
+type Cache struct {
+	// Dir is the directory the responses are stored in.
+	Dir string
+
+	// TTLs are the cache lifetimes by endpoint path prefix, the longest
+	// matching prefix wins. If nil, DefaultCacheTTLs is used.
+	TTLs map[string]time.Duration
+
+	// DefaultTTL is used for endpoints matching no prefix of TTLs.
+	DefaultTTL time.Duration
+}
+
+// NewCache returns a cache storing responses in dir with the default TTLs.
+func NewCache(dir string) *Cache {
+	return &Cache{Dir: dir, DefaultTTL: time.Hour}
+}
+
+type cacheEntry struct {
+	Time         time.Time
+	ETag         string
+	LastModified string
+	Data         json.RawMessage
+}
+
+func (e *cacheEntry) fresh(ttl time.Duration) bool {
+	return time.Since(e.Time) < ttl
+}
+
+func (e *cacheEntry) header() http.Header {
+	h := make(http.Header)
+	if e.ETag != "" {
+		h.Set("ETag", e.ETag)
+	}
+	if e.LastModified != "" {
+		h.Set("Last-Modified", e.LastModified)
+	}
+	return h
+}
+
+func (c *Cache) ttl(path string) time.Duration {
+	ttls := c.TTLs
+	if ttls == nil {
+		ttls = DefaultCacheTTLs
+	}
+	ttl, n := c.DefaultTTL, -1
+	for prefix, d := range ttls {
+		if strings.HasPrefix(path, prefix) && len(prefix) > n {
+			ttl, n = d, len(prefix)
+		}
+	}
+	return ttl
+}
+
+func (c *Cache) file(req *http.Request) string {
+	h := sha256.New()
+	io.WriteString(h, req.Method+" "+req.URL.String()+"\n")
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			io.Copy(h, body)
+			body.Close()
+		}
+	}
+	return filepath.Join(c.Dir, hex.EncodeToString(h.Sum(nil))+".json")
+}
+
+func (c *Cache) lookup(req *http.Request) *cacheEntry {
+	b, err := os.ReadFile(c.file(req))
+	if err != nil {
+		return nil
+	}
+	entry := &cacheEntry{}
+	if err := json.Unmarshal(b, entry); err != nil {
+		return nil
+	}
+	return entry
+}
+
+// store writes the entry best effort, a failing cache must not fail the
+// request.
+func (c *Cache) store(req *http.Request, data []byte, header http.Header) {
+	entry := &cacheEntry{
+		Time:         time.Now(),
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+		Data:         data,
+	}
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return
+	}
+	f, err := os.CreateTemp(c.Dir, "*.tmp")
+	if err != nil {
+		return
+	}
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return
+	}
+	if err := os.Rename(f.Name(), c.file(req)); err != nil {
+		os.Remove(f.Name())
+	}
+}
@@ -0,0 +1,117 @@
+package cninfo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCacheTTL(t *testing.T) {
+	c := NewCache(t.TempDir())
+	tests := []struct {
+		path string
+		ttl  time.Duration
+	}{
+		{"/new/data/szse_stock.json", 24 * time.Hour},
+		{"/new/hisAnnouncement/query", time.Hour},
+		{"/data20/financialData/getMainIndicators", 24 * time.Hour},
+		{"/new/fulltextSearch", time.Hour},
+	}
+	for _, tt := range tests {
+		if ttl := c.ttl(tt.path); ttl != tt.ttl {
+			t.Errorf("got ttl %s of %s, want %s", ttl, tt.path, tt.ttl)
+		}
+	}
+
+	c.TTLs = map[string]time.Duration{"/data20/": time.Minute, "/data20/financialData/": time.Second}
+	if ttl := c.ttl("/data20/financialData/getBalanceSheets"); ttl != time.Second {
+		t.Errorf("got ttl %s, want the one of the longest prefix", ttl)
+	}
+	if ttl := c.ttl("/new/data/szse_stock.json"); ttl != c.DefaultTTL {
+		t.Errorf("got ttl %s, want DefaultTTL", ttl)
+	}
+}
+
+// age moves the time of every entry in the cache back by d.
+func age(t *testing.T, c *Cache, d time.Duration) {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(c.Dir, "*.json"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("got cache files %v, %v", files, err)
+	}
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry := &cacheEntry{}
+		if err := json.Unmarshal(b, entry); err != nil {
+			t.Fatal(err)
+		}
+		entry.Time = entry.Time.Add(-d)
+		if b, err = json.Marshal(entry); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCacheRevalidate(t *testing.T) {
+	const etag, lastModified = `"v1"`, "Thu, 20 Apr 2023 08:00:00 GMT"
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		w.Write([]byte(`{"stockList":[{"code":"000001","orgId":"gssz0000001","zwjc":"平安银行"}]}`))
+	}))
+	defer server.Close()
+
+	s := &Source{BaseURL: server.URL, Limiter: NewRateLimiter(0, 1), Cache: NewCache(t.TempDir())}
+	get := func(wantRequests int) {
+		t.Helper()
+		stocks, err := s.GetStockList()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(stocks) != 1 || stocks[0].Code != "000001" {
+			t.Fatalf("got stocks %+v", stocks)
+		}
+		if len(requests) != wantRequests {
+			t.Fatalf("got %d requests, want %d", len(requests), wantRequests)
+		}
+	}
+
+	get(1)
+	if r := requests[0]; r.Header.Get("If-None-Match") != "" || r.Header.Get("If-Modified-Since") != "" {
+		t.Fatalf("got conditional first request %v", r.Header)
+	}
+
+	// The stock list lives for a day, longer than DefaultTTL.
+	age(t, s.Cache, 2*time.Hour)
+	get(1)
+
+	age(t, s.Cache, 23*time.Hour)
+	get(2)
+	if r := requests[1]; r.Header.Get("If-None-Match") != etag || r.Header.Get("If-Modified-Since") != lastModified {
+		t.Fatalf("got revalidation headers %v", r.Header)
+	}
+
+	// The 304 makes the entry fresh again and keeps its validators.
+	get(2)
+	age(t, s.Cache, 25*time.Hour)
+	get(3)
+	if r := requests[2]; r.Header.Get("If-None-Match") != etag {
+		t.Fatalf("got revalidation headers %v after a 304", r.Header)
+	}
+}
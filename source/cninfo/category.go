@@ -0,0 +1,64 @@
+package cninfo
+
+import (
+	"fmt"
+)
+
+// Category is the announcement category filter of the announcement query.
+type Category string
+
+const (
+	CategoryAnnualReport       Category = "category_ndbg_szsh"
+	CategorySemiAnnualReport   Category = "category_bndbg_szsh"
+	CategoryFirstQuarterReport Category = "category_yjdbg_szsh"
+	CategoryThirdQuarterReport Category = "category_sjdbg_szsh"
+	CategoryEarningsForecast   Category = "category_yjygjxz_szsh"
+	CategoryEquityDistribution Category = "category_qyfpxzcs_szsh"
+)
+
+// PeriodicReportCategories are the categories of the periodic reports, in
+// the order of the fiscal year.
+var PeriodicReportCategories = []Category{
+	CategoryFirstQuarterReport,
+	CategorySemiAnnualReport,
+	CategoryThirdQuarterReport,
+	CategoryAnnualReport,
+}
+
+var categoryNames = map[Category]string{
+	CategoryAnnualReport:       "annual",
+	CategorySemiAnnualReport:   "semi-annual",
+	CategoryFirstQuarterReport: "q1",
+	CategoryThirdQuarterReport: "q3",
+	CategoryEarningsForecast:   "earnings-forecast",
+	CategoryEquityDistribution: "equity-distribution",
+}
+
+// Name returns the short name of c, e.g. "annual", or c itself if unknown.
+func (c Category) Name() string {
+	if name, ok := categoryNames[c]; ok {
+		return name
+	}
+	return string(c)
+}
+
+// IsPeriodicReport reports whether c is one of PeriodicReportCategories.
+func (c Category) IsPeriodicReport() bool {
+	for _, category := range PeriodicReportCategories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseCategory accepts either a short name returned by Category.Name or a
+// raw cninfo category value.
+func ParseCategory(s string) (Category, error) {
+	for category, name := range categoryNames {
+		if s == name || s == string(category) {
+			return category, nil
+		}
+	}
+	return "", fmt.Errorf("unknown category %q", s)
+}
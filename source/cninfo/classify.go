@@ -0,0 +1,205 @@
+package cninfo
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// ReportKind tells a periodic report from its summary, attachments and
+// the notices filed under the same category.
+type ReportKind string
+
+const (
+	KindFull       ReportKind = "full"
+	KindSummary    ReportKind = "summary"
+	KindAttachment ReportKind = "attachment"
+	KindOther      ReportKind = "other"
+)
+
+// ReportKinds are all report kinds.
+var ReportKinds = []ReportKind{KindFull, KindSummary, KindAttachment, KindOther}
+
+// Language is the language of an announcement.
+type Language string
+
+const (
+	LanguageChinese Language = "zh"
+	LanguageEnglish Language = "en"
+)
+
+// Languages are all languages.
+var Languages = []Language{LanguageChinese, LanguageEnglish}
+
+// Revision tells an original announcement from a re-published one.
+type Revision string
+
+const (
+	RevisionOriginal  Revision = "original"
+	RevisionCorrected Revision = "corrected"
+	RevisionRevised   Revision = "revised"
+	RevisionUpdated   Revision = "updated"
+)
+
+// TitleClass is what the title of an announcement tells about it.
+type TitleClass struct {
+	Kind     ReportKind
+	Language Language
+	Revision Revision
+
+	// Superseded is set for cancelled announcements, and by
+	// ClassifyAnnouncements for the ones re-published later.
+	Superseded bool
+}
+
+func (c *TitleClass) String() string {
+	s := fmt.Sprintf("%s/%s/%s", c.Kind, c.Language, c.Revision)
+	if c.Superseded {
+		s += "/superseded"
+	}
+	return s
+}
+
+var (
+	revisionWords = []struct {
+		revision Revision
+		words    []string
+	}{
+		{RevisionCorrected, []string{"更正后", "更正版", "更正稿", "CORRECTED"}},
+		{RevisionRevised, []string{"修订版", "修订稿", "修订后", "REVISED"}},
+		{RevisionUpdated, []string{"更新后", "更新版", "更新稿", "UPDATED"}},
+	}
+	cancelWords  = []string{"已取消", "已作废", "已撤销", "CANCELLED"}
+	summaryWords = []string{"摘要", "SUMMARY"}
+	englishWords = []string{"英文版", "英文", "ENGLISH VERSION"}
+	noticeWords  = []string{"公告", "提示性", "说明", "问询", "回复", "意见", "NOTICE", "ANNOUNCEMENT"}
+
+	attachmentRegexp = regexp.MustCompile(`之?附件\d*|附表|ATTACHMENT`)
+	variantRegexp    = regexp.MustCompile(`[（(][^）)]*[）)]`)
+)
+
+func containsAny(s string, words []string) bool {
+	for _, word := range words {
+		if strings.Contains(s, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// isEnglish reports whether s is written in latin letters rather than
+// Chinese, e.g. "2022 Annual Report".
+func isEnglish(s string) bool {
+	var latin, han int
+	for _, r := range s {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.IsLetter(r):
+			latin++
+		}
+	}
+	return latin > 0 && han == 0
+}
+
+// ClassifyTitle classifies an announcement by its title, e.g.
+// "2022年年度报告摘要（更新后）" is an updated Chinese summary.
+func ClassifyTitle(title string) *TitleClass {
+	upper := strings.ToUpper(title)
+	c := &TitleClass{Kind: KindFull, Language: LanguageChinese, Revision: RevisionOriginal}
+	if containsAny(upper, englishWords) || isEnglish(title) {
+		c.Language = LanguageEnglish
+	}
+	for _, r := range revisionWords {
+		if containsAny(upper, r.words) {
+			c.Revision = r.revision
+			break
+		}
+	}
+	c.Superseded = containsAny(upper, cancelWords)
+
+	// The kind is told by what the title is about once the variant notes
+	// in brackets are removed: "关于2022年年度报告的更正公告" is a notice.
+	base := variantRegexp.ReplaceAllString(upper, "")
+	switch {
+	case attachmentRegexp.MatchString(base):
+		c.Kind = KindAttachment
+	case strings.HasPrefix(base, "关于") || containsAny(base, noticeWords):
+		c.Kind = KindOther
+	case containsAny(base, summaryWords):
+		c.Kind = KindSummary
+	}
+	return c
+}
+
+// Classify classifies a by its title.
+func (a *Announcement) Classify() *TitleClass {
+	return ClassifyTitle(a.AnnouncementTitle)
+}
+
+// variantKey is the title of a without the variant notes and the stock name
+// prefix, so that the versions of the same report share it.
+func variantKey(a *Announcement, c *TitleClass) string {
+	title := a.AnnouncementTitle
+	for _, sep := range []string{"：", ":"} {
+		if i := strings.LastIndex(title, sep); i >= 0 {
+			title = title[i+len(sep):]
+		}
+	}
+	title = variantRegexp.ReplaceAllString(title, "")
+	for _, r := range revisionWords {
+		for _, word := range r.words {
+			title = strings.ReplaceAll(title, word, "")
+		}
+	}
+	return fmt.Sprintf("%s/%s/%s", c.Kind, c.Language, strings.Join(strings.Fields(title), ""))
+}
+
+// ClassifyAnnouncements classifies announcements and marks every
+// announcement superseded when the same report of the same kind and
+// language is published again later, e.g. "2022年年度报告" by
+// "2022年年度报告（更新后）".
+func ClassifyAnnouncements(announcements []*Announcement) []*TitleClass {
+	classes := make([]*TitleClass, len(announcements))
+	latest := make(map[string]int)
+	for i, a := range announcements {
+		classes[i] = a.Classify()
+		if classes[i].Superseded {
+			continue
+		}
+		key := variantKey(a, classes[i])
+		j, ok := latest[key]
+		if !ok {
+			latest[key] = i
+			continue
+		}
+		if a.AnnouncementTime > announcements[j].AnnouncementTime || a.AnnouncementTime == announcements[j].AnnouncementTime && classes[i].Revision != RevisionOriginal {
+			classes[j].Superseded = true
+			latest[key] = i
+		} else {
+			classes[i].Superseded = true
+		}
+	}
+	return classes
+}
+
+// ParseReportKind parses a report kind such as "summary".
+func ParseReportKind(s string) (ReportKind, error) {
+	for _, kind := range ReportKinds {
+		if s == string(kind) {
+			return kind, nil
+		}
+	}
+	return "", fmt.Errorf("unknown report kind %q", s)
+}
+
+// ParseLanguage parses a language such as "en".
+func ParseLanguage(s string) (Language, error) {
+	for _, language := range Languages {
+		if s == string(language) {
+			return language, nil
+		}
+	}
+	return "", fmt.Errorf("unknown language %q", s)
+}
@@ -0,0 +1,68 @@
+package cninfo
+
+import (
+	"testing"
+)
+
+func TestClassifyTitle(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"2022年年度报告", "full/zh/original"},
+		{"2022年年度报告摘要", "summary/zh/original"},
+		{"2022年年度报告（英文版）", "full/en/original"},
+		{"2022 Annual Report", "full/en/original"},
+		{"2022年年度报告摘要（更新后）", "summary/zh/updated"},
+		{"2022年年度报告（修订版）", "full/zh/revised"},
+		{"2022年年度报告（更正后）", "full/zh/corrected"},
+		{"2022年年度报告（已取消）", "full/zh/original/superseded"},
+		{"2022年年度报告之附件1", "attachment/zh/original"},
+		{"关于2022年年度报告的更正公告", "other/zh/original"},
+	}
+	for _, tt := range tests {
+		if got := ClassifyTitle(tt.title).String(); got != tt.want {
+			t.Errorf("ClassifyTitle(%q) = %s, want %s", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestClassifyAnnouncements(t *testing.T) {
+	announcements := []*Announcement{
+		{AnnouncementTitle: "2022年年度报告", AnnouncementTime: 1},
+		{AnnouncementTitle: "2022年年度报告摘要", AnnouncementTime: 1},
+		{AnnouncementTitle: "平安银行：2022年年度报告（更新后）", AnnouncementTime: 2},
+		{AnnouncementTitle: "2022年年度报告（英文版）", AnnouncementTime: 2},
+	}
+	classes := ClassifyAnnouncements(announcements)
+	for i, want := range []bool{true, false, false, false} {
+		if classes[i].Superseded != want {
+			t.Errorf("%s superseded = %v, want %v", announcements[i].AnnouncementTitle, classes[i].Superseded, want)
+		}
+	}
+}
+
+func TestMatchHKCategory(t *testing.T) {
+	announcements := []*Announcement{
+		{AnnouncementTitle: "2022年度报告"},
+		{AnnouncementTitle: "2023半年度报告"},
+		{AnnouncementTitle: "2023年半年报"},
+		{AnnouncementTitle: "2023 Interim Report"},
+		{AnnouncementTitle: "2022 Annual Report"},
+		{AnnouncementTitle: "2023 Semi-Annual Report"},
+		{AnnouncementTitle: "INTERIM REPORT FOR THE SIX MONTHS ENDED 30 JUNE 2023 (ANNUAL DIVIDEND)"},
+	}
+	titles := func(announcements []*Announcement) string {
+		var s string
+		for _, announcement := range announcements {
+			s += announcement.AnnouncementTitle + ";"
+		}
+		return s
+	}
+	if got, want := titles(matchHKCategory(CategoryAnnualReport, announcements)), "2022年度报告;2022 Annual Report;"; got != want {
+		t.Errorf("got annual reports %s, want %s", got, want)
+	}
+	if got, want := titles(matchHKCategory(CategorySemiAnnualReport, announcements)), "2023半年度报告;2023年半年报;2023 Interim Report;2023 Semi-Annual Report;INTERIM REPORT FOR THE SIX MONTHS ENDED 30 JUNE 2023 (ANNUAL DIVIDEND);"; got != want {
+		t.Errorf("got interim reports %s, want %s", got, want)
+	}
+}
@@ -2,12 +2,13 @@ package cninfo
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
-	"strconv"
 	"strings"
 	"time"
 )
@@ -18,6 +19,7 @@ type Stock struct {
 	Category string `json:"category"`
 	OrgID    string `json:"orgId"`
 	Zwjc     string `json:"zwjc"`
+	Market   Market `json:"market,omitempty"`
 }
 
 type StockListResponse struct {
@@ -89,6 +91,10 @@ func (r *HisDividendResponse) GetCodeString() string {
 	return fmt.Sprintf("%v", r.Code)
 }
 
+// FormURLEncoded encodes q with the defaults of the cninfo search page for
+// the page, column, tab and date range. An empty Stock or Category is not
+// filtered on, an empty request queries every A-share announcement of the
+// last year.
 func (q *HisAnnouncementQueryRequest) FormURLEncoded() string {
 	v := *q
 	if v.PageNum == 0 {
@@ -98,47 +104,247 @@ func (q *HisAnnouncementQueryRequest) FormURLEncoded() string {
 		v.PageSize = 30
 	}
 	if v.Column == "" {
-		v.Column = "szse"
+		v.Column = string(MarketAShare)
 	}
 	if v.TabName == "" {
 		v.TabName = "fulltext"
 	}
-	if v.Stock == "" {
-		v.Stock = "000001,gssz0000001"
-	}
-	if v.Category == "" {
-		v.Category = "category_ndbg_szsh"
-	}
 	if v.IsHLtitle == "" {
 		v.IsHLtitle = "true"
 	}
 	if v.SeDate == "" {
-		now := time.Now()
-		v.SeDate = fmt.Sprintf("%s~%s", now.AddDate(-1, 0, 0).Format("2006-01-02"), now.AddDate(0, 0, 1).Format("2006-01-02"))
+		now := Now()
+		v.SeDate = fmt.Sprintf("%s~%s", formatDate(now.AddDate(-1, 0, 0)), formatDate(now.AddDate(0, 0, 1)))
 	}
 	return fmt.Sprintf("pageNum=%d&pageSize=%d&column=%s&tabName=%s&plate=%s&stock=%s&searchkey=%s&secid=%s&category=%s&trade=%s&seDate=%s&sortName=%s&sortType=%s&isHLtitle=%s", v.PageNum, v.PageSize,
 		url.QueryEscape(v.Column), url.QueryEscape(v.TabName), url.QueryEscape(v.Plate), url.QueryEscape(v.Stock), url.QueryEscape(v.Searchkey), url.QueryEscape(v.Secid), url.QueryEscape(v.Category),
 		url.QueryEscape(v.Trade), v.SeDate, url.QueryEscape(v.SortName), url.QueryEscape(v.SortType), url.QueryEscape(v.IsHLtitle))
 }
 
+const (
+	DefaultBaseURL   = "http://www.cninfo.com.cn"
+	DefaultStaticURL = "http://static.cninfo.com.cn"
+	DefaultUserAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/15.4 Safari/605.1.15"
+)
+
+// Source requests cninfo. The zero value is ready to use and talks to the
+// public cninfo site with http.DefaultClient.
 type Source struct {
+	// Client is used to send requests. If nil, http.DefaultClient is used.
+	Client *http.Client
+
+	// BaseURL is the root of the www endpoints (stock list, announcement
+	// query). If empty, DefaultBaseURL is used.
+	BaseURL string
+
+	// DataURL is the root of the data20 endpoints. If empty, BaseURL is used.
+	DataURL string
+
+	// StaticURL is the root of the adjunct files. If empty, DefaultStaticURL
+	// is used.
+	StaticURL string
+
+	// UserAgent is sent with every request. If empty, DefaultUserAgent is used.
+	UserAgent string
+
+	// Timeout limits each attempt of a request, including reading the
+	// response body. Zero means no limit other than the one of the context
+	// and Client.
+	Timeout time.Duration
+
+	// Limiter throttles the requests. If nil, DefaultRateLimiter is used.
+	Limiter *RateLimiter
+
+	// Retry decides which failed requests are sent again. If nil,
+	// DefaultRetryPolicy is used.
+	Retry *RetryPolicy
+
+	// DownloadProgress, if set, is called while an adjunct is downloaded
+	// with the bytes written so far and the total size, or -1 if unknown.
+	DownloadProgress func(announcement *Announcement, written, total int64)
+
+	// Cache, if set, keeps the responses on disk. Adjunct downloads are
+	// never cached.
+	Cache *Cache
 }
 
-func (s *Source) RequestStockList() (*StockListResponse, error) {
-	resp, err := http.Get("http://www.cninfo.com.cn/new/data/szse_stock.json")
+func (s *Source) client() *http.Client {
+	if s.Client != nil {
+		return s.Client
+	}
+	return http.DefaultClient
+}
+
+func (s *Source) baseURL() string {
+	if s.BaseURL != "" {
+		return strings.TrimSuffix(s.BaseURL, "/")
+	}
+	return DefaultBaseURL
+}
+
+func (s *Source) dataURL() string {
+	if s.DataURL != "" {
+		return strings.TrimSuffix(s.DataURL, "/")
+	}
+	return s.baseURL()
+}
+
+func (s *Source) staticURL() string {
+	if s.StaticURL != "" {
+		return strings.TrimSuffix(s.StaticURL, "/")
+	}
+	return DefaultStaticURL
+}
+
+func (s *Source) userAgent() string {
+	if s.UserAgent != "" {
+		return s.UserAgent
+	}
+	return DefaultUserAgent
+}
+
+// AdjunctURL returns the absolute URL of the adjunct file of announcement.
+// Absolute adjunct URLs are returned as is.
+func (s *Source) AdjunctURL(announcement *Announcement) string {
+	if strings.HasPrefix(announcement.AdjunctURL, "http://") || strings.HasPrefix(announcement.AdjunctURL, "https://") {
+		return announcement.AdjunctURL
+	}
+	return s.staticURL() + "/" + strings.TrimPrefix(announcement.AdjunctURL, "/")
+}
+
+func (s *Source) newRequest(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, err
 	}
+	req.Header.Set("Accept", "*/*")
+	req.Header.Set("Accept-Language", "zh-CN,zh-Hans;q=0.9")
+	req.Header.Set("User-Agent", s.userAgent())
+	return req, nil
+}
+
+func (s *Source) limiter() *RateLimiter {
+	if s.Limiter != nil {
+		return s.Limiter
+	}
+	return DefaultRateLimiter
+}
+
+func (s *Source) retry() *RetryPolicy {
+	if s.Retry != nil {
+		return s.Retry
+	}
+	return DefaultRetryPolicy
+}
+
+// codeResponse is a data20 response, which carries a code of its own.
+type codeResponse interface {
+	GetCodeString() string
+}
+
+// cacheable reports whether the response decoded into v may be cached.
+// data20 error replies such as "system busy" are temporary.
+func cacheable(v any) bool {
+	r, ok := v.(codeResponse)
+	return !ok || checkCode(r.GetCodeString(), "") == nil
+}
+
+func (s *Source) do(ctx context.Context, req *http.Request, v any) error {
+	var entry *cacheEntry
+	if s.Cache != nil {
+		entry = s.Cache.lookup(req)
+		if entry != nil && entry.fresh(s.Cache.ttl(req.URL.Path)) {
+			if err := json.Unmarshal(entry.Data, v); err != nil {
+				return &SchemaError{URL: req.URL.String(), Err: err}
+			}
+			if cacheable(v) {
+				return nil
+			}
+			entry = nil
+		}
+	}
+
+	var b []byte
+	var header http.Header
+	err := s.retry().Do(ctx, func() (err error) {
+		b, header, err = s.send(ctx, req, entry)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return &SchemaError{URL: req.URL.String(), Err: err}
+	}
+	if s.Cache != nil && cacheable(v) {
+		s.Cache.store(req, b, header)
+	}
+	return nil
+}
+
+// send sends req once and returns the response body. If entry is not nil
+// the request is made conditional on it and its data is returned when the
+// response is not modified.
+func (s *Source) send(ctx context.Context, req *http.Request, entry *cacheEntry) ([]byte, http.Header, error) {
+	if err := s.limiter().Wait(ctx); err != nil {
+		return nil, nil, err
+	}
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+	r := req.Clone(ctx)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, nil, err
+		}
+		r.Body = body
+	}
+	if entry != nil {
+		if entry.ETag != "" {
+			r.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			r.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+	resp, err := s.client().Do(r)
+	if err != nil {
+		return nil, nil, err
+	}
 	defer resp.Body.Close()
-	p := &StockListResponse{}
-	if err := json.NewDecoder(resp.Body).Decode(p); err != nil {
-		return nil, err
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		return entry.Data, entry.header(), nil
 	}
-	return p, nil
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if t := bytes.TrimSpace(b); len(t) > 0 && t[0] == '<' {
+		return nil, nil, ErrAntiBot
+	}
+	return b, resp.Header, nil
+}
+
+func (s *Source) RequestStockList() (*StockListResponse, error) {
+	return s.RequestStockListContext(context.Background())
+}
+
+func (s *Source) RequestStockListContext(ctx context.Context) (*StockListResponse, error) {
+	return s.RequestMarketStockListContext(ctx, MarketAShare)
 }
 
 func (s *Source) GetStockList() ([]*Stock, error) {
-	p, err := s.RequestStockList()
+	return s.GetStockListContext(context.Background())
+}
+
+func (s *Source) GetStockListContext(ctx context.Context) ([]*Stock, error) {
+	p, err := s.RequestStockListContext(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -146,36 +352,41 @@ func (s *Source) GetStockList() ([]*Stock, error) {
 }
 
 func (s *Source) RequestHisAnnouncementQuery(q *HisAnnouncementQueryRequest) (*HisAnnouncementQueryResponse, error) {
-	client := &http.Client{}
+	return s.RequestHisAnnouncementQueryContext(context.Background(), q)
+}
+
+func (s *Source) RequestHisAnnouncementQueryContext(ctx context.Context, q *HisAnnouncementQueryRequest) (*HisAnnouncementQueryResponse, error) {
 	form := q.FormURLEncoded()
-	req, err := http.NewRequest("POST", "http://www.cninfo.com.cn/new/hisAnnouncement/query", bytes.NewBufferString(form))
+	req, err := s.newRequest(ctx, "POST", s.baseURL()+"/new/hisAnnouncement/query", strings.NewReader(form))
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=UTF-8")
-	req.Header.Set("Accept", "*/*")
-	req.Header.Set("Accept-Language", "zh-CN,zh-Hans;q=0.9")
-	req.Header.Set("Host", "www.cninfo.com.cn")
-	req.Header.Set("Origin", "http://www.cninfo.com.cn")
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/15.4 Safari/605.1.15")
+	req.Header.Set("Origin", s.baseURL())
 	req.Header.Set("Connection", "keep-alive")
-	req.Header.Set("Referer", "http://www.cninfo.com.cn/new/commonUrl/pageOfSearch?url=disclosure/list/search&lastPage=index")
-	req.Header.Set("Content-Length", strconv.Itoa(len(form)))
+	req.Header.Set("Referer", s.baseURL()+"/new/commonUrl/pageOfSearch?url=disclosure/list/search&lastPage=index")
 	req.Header.Set("X-Requested-With", "XMLHttpRequest")
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
 	p := &HisAnnouncementQueryResponse{}
-	if err := json.NewDecoder(resp.Body).Decode(p); err != nil {
+	if err := s.do(ctx, req, p); err != nil {
 		return nil, err
 	}
 	return p, nil
 }
 
 func (s *Source) GetAnnualReportAnnoucements(stock *Stock, start, end time.Time) ([]*Announcement, error) {
-	announcements := []*Announcement{}
+	return s.GetPeriodicReportAnnouncementsContext(context.Background(), stock, CategoryAnnualReport, start, end)
+}
+
+func (s *Source) GetAnnualReportAnnoucementsContext(ctx context.Context, stock *Stock, start, end time.Time) ([]*Announcement, error) {
+	return s.GetPeriodicReportAnnouncementsContext(ctx, stock, CategoryAnnualReport, start, end)
+}
+
+func (s *Source) GetPeriodicReportAnnouncements(stock *Stock, category Category, start, end time.Time) ([]*Announcement, error) {
+	return s.GetPeriodicReportAnnouncementsContext(context.Background(), stock, category, start, end)
+}
+
+func (s *Source) GetPeriodicReportAnnouncementsContext(ctx context.Context, stock *Stock, category Category, start, end time.Time) ([]*Announcement, error) {
+	var announcements []*Announcement
 	if start.After(end) {
 		return nil, errors.New("start time must be before end time")
 	}
@@ -183,26 +394,28 @@ func (s *Source) GetAnnualReportAnnoucements(stock *Stock, start, end time.Time)
 		return nil, errors.New("time range must be less than 30 years")
 	}
 
+	q := &HisAnnouncementQueryRequest{
+		Column:   stock.Market.column(),
+		Stock:    strings.Join([]string{stock.Code, stock.OrgID}, ","),
+		Category: string(category),
+	}
+	if stock.Market == MarketHK {
+		if _, ok := hkCategoryTitles[category]; !ok {
+			return nil, fmt.Errorf("category %s is not supported for market %s", category.Name(), MarketHK.Name())
+		}
+		q.Category = ""
+	}
 	from := start
 	to := from.AddDate(3, 0, 0)
 	if to.After(end) {
 		to = end
 	}
 	for {
-		p, err := s.RequestHisAnnouncementQuery(&HisAnnouncementQueryRequest{
-			Stock:    strings.Join([]string{stock.Code, stock.OrgID}, ","),
-			Category: "category_ndbg_szsh",
-			SeDate:   fmt.Sprintf("%s~%s", from.Format("2006-01-02"), to.Format("2006-01-02")),
-		})
+		p, err := s.queryAnnouncementWindow(ctx, q, from, to)
 		if err != nil {
 			return nil, err
 		}
-		c := len(p.Announcements)
-		if c > 0 {
-			for i := c - 1; i >= 0; i-- {
-				announcements = append(announcements, p.Announcements[i])
-			}
-		}
+		announcements = append(announcements, p...)
 		if to.Equal(end) || to.After(end) {
 			break
 		}
@@ -212,35 +425,112 @@ func (s *Source) GetAnnualReportAnnoucements(stock *Stock, start, end time.Time)
 			to = end
 		}
 	}
-	return announcements, nil
+	if stock.Market == MarketHK {
+		return matchHKCategory(category, uniqueAnnouncements(announcements)), nil
+	}
+	return uniqueAnnouncements(announcements), nil
 }
 
-func (s *Source) RequestHisDividend(stockCode string) (*HisDividendResponse, error) {
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", "http://www.cninfo.com.cn/data20/companyOverview/getCompanyHisDividend?scode="+stockCode, nil)
+const (
+	queryPageSize = 30
+	queryMaxPages = 100
+)
+
+var errIncomplete = errors.New("incomplete announcements")
+
+// queryAnnouncementWindow returns every announcement matching q published
+// between from and to, oldest first. The window is split in halves while
+// cninfo reports more records than it lets us page through.
+func (s *Source) queryAnnouncementWindow(ctx context.Context, q *HisAnnouncementQueryRequest, from, to time.Time) ([]*Announcement, error) {
+	r := *q
+	r.PageNum = 1
+	r.PageSize = queryPageSize
+	r.SeDate = fmt.Sprintf("%s~%s", formatDate(from), formatDate(to))
+	p, err := s.RequestHisAnnouncementQueryContext(ctx, &r)
 	if err != nil {
 		return nil, err
 	}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
+	if p.TotalAnnouncement > queryPageSize*queryMaxPages && to.Sub(from) >= 24*time.Hour {
+		days := int(to.Sub(from).Hours() / 24)
+		mid := from.AddDate(0, 0, days/2)
+		older, err := s.queryAnnouncementWindow(ctx, q, from, mid)
+		if err != nil {
+			return nil, err
+		}
+		newer, err := s.queryAnnouncementWindow(ctx, q, mid.AddDate(0, 0, 1), to)
+		if err != nil {
+			return nil, err
+		}
+		return append(older, newer...), nil
 	}
-	defer resp.Body.Close()
+
+	var announcements []*Announcement
+	for {
+		announcements = append(announcements, p.Announcements...)
+		if !p.HasMore || r.PageNum >= p.Totalpages || len(p.Announcements) == 0 {
+			break
+		}
+		r.PageNum++
+		if p, err = s.RequestHisAnnouncementQueryContext(ctx, &r); err != nil {
+			return nil, err
+		}
+	}
+	announcements = uniqueAnnouncements(announcements)
+	if len(announcements) < p.TotalAnnouncement {
+		return nil, fmt.Errorf("%w %s, got %d of %d", errIncomplete, r.SeDate, len(announcements), p.TotalAnnouncement)
+	}
+	for i, j := 0, len(announcements)-1; i < j; i, j = i+1, j-1 {
+		announcements[i], announcements[j] = announcements[j], announcements[i]
+	}
+	return announcements, nil
+}
+
+// uniqueAnnouncements drops every announcement whose AnnouncementID was
+// already seen, keeping the order of the first occurrences.
+func uniqueAnnouncements(announcements []*Announcement) []*Announcement {
+	seen := make(map[string]struct{}, len(announcements))
+	unique := announcements[:0]
+	for _, announcement := range announcements {
+		if _, ok := seen[announcement.AnnouncementID]; ok {
+			continue
+		}
+		seen[announcement.AnnouncementID] = struct{}{}
+		unique = append(unique, announcement)
+	}
+	return unique
+}
+
+func (s *Source) RequestHisDividend(stockCode string) (*HisDividendResponse, error) {
+	return s.RequestHisDividendContext(context.Background(), stockCode)
+}
+
+func (s *Source) RequestHisDividendContext(ctx context.Context, stockCode string) (*HisDividendResponse, error) {
 	p := &HisDividendResponse{}
-	if err := json.NewDecoder(resp.Body).Decode(p); err != nil {
+	if err := s.getData20(ctx, "/companyOverview/getCompanyHisDividend", stockCode, p); err != nil {
 		return nil, err
 	}
 	return p, nil
 }
 
+func (s *Source) getData20(ctx context.Context, path, stockCode string, v any) error {
+	req, err := s.newRequest(ctx, "GET", s.dataURL()+"/data20"+path+"?scode="+url.QueryEscape(stockCode), nil)
+	if err != nil {
+		return err
+	}
+	return s.do(ctx, req, v)
+}
+
 func (s *Source) GetDividendRecords(stock *Stock) ([]*DividendRecord, error) {
-	p, err := s.RequestHisDividend(stock.Code)
+	return s.GetDividendRecordsContext(context.Background(), stock)
+}
+
+func (s *Source) GetDividendRecordsContext(ctx context.Context, stock *Stock) ([]*DividendRecord, error) {
+	p, err := s.RequestHisDividendContext(ctx, stock.Code)
 	if err != nil {
 		return nil, err
 	}
-	code := p.GetCodeString()
-	if code != "200" {
-		return nil, fmt.Errorf("code: %s, msg: %s", code, p.Msg)
+	if err := checkCode(p.GetCodeString(), p.Msg); err != nil {
+		return nil, err
 	}
 	return p.Data.Records, nil
 }
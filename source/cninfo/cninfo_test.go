@@ -0,0 +1,194 @@
+package cninfo_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chamzzzzzz/financial/source/cninfo"
+	"github.com/chamzzzzzz/financial/source/cninfo/cninfotest"
+	"github.com/chamzzzzzz/financial/source/cninfo/replay/replaytest"
+)
+
+func TestGetStockList(t *testing.T) {
+	source := replaytest.NewSource(t, filepath.Join("testdata", "stock_list.json"))
+	stocks, err := source.GetStockList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stocks) != 3 {
+		t.Fatalf("got %d stocks, want 3", len(stocks))
+	}
+	stock := stocks[0]
+	if stock.Code != "000001" || stock.OrgID != "gssz0000001" || stock.Zwjc != "平安银行" || stock.Market != cninfo.MarketAShare {
+		t.Errorf("got stock %+v", stock)
+	}
+}
+
+func TestGetAnnualReportAnnoucements(t *testing.T) {
+	source := replaytest.NewSource(t, filepath.Join("testdata", "annual_report_announcements.json"))
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, cninfo.Location)
+	end := time.Date(2023, 6, 30, 0, 0, 0, 0, cninfo.Location)
+	announcements, err := source.GetAnnualReportAnnoucements(&cninfo.Stock{Code: "000001", OrgID: "gssz0000001"}, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(announcements) != 32 {
+		t.Fatalf("got %d announcements, want 32 across two pages", len(announcements))
+	}
+	seen := make(map[string]bool)
+	for i, announcement := range announcements {
+		if seen[announcement.AnnouncementID] {
+			t.Errorf("duplicate announcement %s", announcement.AnnouncementID)
+		}
+		seen[announcement.AnnouncementID] = true
+		if i > 0 && announcement.AnnouncementTime < announcements[i-1].AnnouncementTime {
+			t.Errorf("announcement %s is older than its predecessor", announcement.AnnouncementID)
+		}
+	}
+	if got := announcements[0].Time().Format("2006-01-02 15:04"); got != "2021-03-09 00:00" {
+		t.Errorf("got first publish time %s, want 2021-03-09 00:00", got)
+	}
+}
+
+func TestGetDividendRecords(t *testing.T) {
+	source := replaytest.NewSource(t, filepath.Join("testdata", "dividend.json"))
+	records, err := source.GetDividendRecords(&cninfo.Stock{Code: "000001"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("got %d records, want 4", len(records))
+	}
+	payDate, err := records[0].PayTime()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2023, 6, 14, 0, 0, 0, 0, cninfo.Location); !payDate.Equal(want) {
+		t.Errorf("got pay date %s, want %s", payDate, want)
+	}
+	if payDate, err := records[3].PayTime(); err != nil || !payDate.IsZero() {
+		t.Errorf("got pay date %s, %v, want zero time", payDate, err)
+	}
+
+	var apiErr *cninfo.APIError
+	if _, err := source.GetDividendRecords(&cninfo.Stock{Code: "999999"}); !errors.As(err, &apiErr) || apiErr.Code != "500" {
+		t.Errorf("got error %v for unknown stock, want APIError with code 500", err)
+	}
+}
+
+func TestCacheSkipsAPIErrors(t *testing.T) {
+	source := replaytest.NewSource(t, filepath.Join("testdata", "dividend.json"))
+	source.Cache = cninfo.NewCache(t.TempDir())
+	if _, err := source.GetDividendRecords(&cninfo.Stock{Code: "999999"}); err == nil {
+		t.Fatal("got no error for unknown stock")
+	}
+	if entries, _ := os.ReadDir(source.Cache.Dir); len(entries) != 0 {
+		t.Fatalf("got %d cache entries after an API error, want 0", len(entries))
+	}
+	if _, err := source.GetDividendRecords(&cninfo.Stock{Code: "000001"}); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(source.Cache.Dir); len(entries) != 1 {
+		t.Fatalf("got %d cache entries, want 1", len(entries))
+	}
+}
+
+func TestQueryAnnouncementWindow(t *testing.T) {
+	day := time.Date(2023, 4, 20, 0, 0, 0, 0, cninfo.Location)
+	stock := &cninfo.Stock{Code: "000001", OrgID: "gssz0000001"}
+	var announcements []*cninfo.Announcement
+	for i := 0; i < 3100; i++ {
+		announcements = append(announcements, &cninfo.Announcement{
+			AnnouncementID:   fmt.Sprint(i),
+			SecCode:          stock.Code,
+			AnnouncementTime: day.AddDate(0, 0, i%2).Add(time.Duration(i) * time.Second).UnixMilli(),
+		})
+	}
+	server := cninfotest.NewServer(&cninfotest.Dataset{
+		Announcements: map[cninfo.Category][]*cninfo.Announcement{cninfo.CategoryAnnualReport: announcements},
+	})
+	defer server.Close()
+
+	// Over the 100 pages cninfo serves, the two days are queried apart.
+	got, err := server.Source().GetAnnualReportAnnoucements(stock, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for _, announcement := range got {
+		seen[announcement.AnnouncementID] = true
+	}
+	if len(got) != 3100 || len(seen) != 3100 {
+		t.Fatalf("got %d announcements, %d unique, want 3100", len(got), len(seen))
+	}
+	if n := server.Requests(); n != 1+52+52 {
+		t.Fatalf("got %d requests, want the window split in two days of 52 pages", n)
+	}
+
+	// A single day cannot be split further.
+	var oneDay []*cninfo.Announcement
+	for _, announcement := range announcements {
+		v := *announcement
+		v.AnnouncementTime = day.Add(time.Duration(len(oneDay)) * time.Second).UnixMilli()
+		oneDay = append(oneDay, &v)
+	}
+	server = cninfotest.NewServer(&cninfotest.Dataset{
+		Announcements: map[cninfo.Category][]*cninfo.Announcement{cninfo.CategoryAnnualReport: oneDay},
+	})
+	defer server.Close()
+	if _, err := server.Source().GetAnnualReportAnnoucements(stock, day, day); !errors.Is(err, cninfo.ErrIncomplete) {
+		t.Fatalf("got error %v, want incomplete announcements", err)
+	}
+}
+
+func TestFormURLEncoded(t *testing.T) {
+	form, err := url.ParseQuery((&cninfo.HisAnnouncementQueryRequest{}).FormURLEncoded())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if form.Get("stock") != "" || form.Get("category") != "" {
+		t.Fatalf("got stock %q and category %q, want no filter", form.Get("stock"), form.Get("category"))
+	}
+	if form.Get("pageNum") != "1" || form.Get("pageSize") != "30" || form.Get("column") != string(cninfo.MarketAShare) || form.Get("seDate") == "" {
+		t.Fatalf("got form %v, want the search page defaults", form)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	day := time.Date(2023, 4, 20, 0, 0, 0, 0, cninfo.Location)
+	var announcements []*cninfo.Announcement
+	for i := 0; i < 3100; i++ {
+		announcements = append(announcements, &cninfo.Announcement{
+			AnnouncementID:   fmt.Sprint(i),
+			SecCode:          "000001",
+			AnnouncementTime: day.AddDate(0, 0, i/1000).Add(time.Duration(i) * time.Second).UnixMilli(),
+		})
+	}
+	server := cninfotest.NewServer(&cninfotest.Dataset{
+		Announcements: map[cninfo.Category][]*cninfo.Announcement{cninfo.CategoryAnnualReport: announcements},
+	})
+	defer server.Close()
+
+	search := func(q *cninfo.SearchQuery) (int, error) {
+		it := server.Source().Search(context.Background(), q)
+		n := 0
+		for it.Next() {
+			n++
+		}
+		return n, it.Err()
+	}
+	n, err := search(&cninfo.SearchQuery{Stock: &cninfo.Stock{Code: "000001"}, Start: day, End: day})
+	if err != nil || n != 1000 {
+		t.Fatalf("got %d announcements, %v, want 1000", n, err)
+	}
+	n, err = search(&cninfo.SearchQuery{Stock: &cninfo.Stock{Code: "000001"}, Start: day, End: day.AddDate(0, 0, 3)})
+	if !errors.Is(err, cninfo.ErrIncomplete) || n != 3000 {
+		t.Fatalf("got %d announcements, %v, want the 3000 reachable and incomplete announcements", n, err)
+	}
+}
@@ -0,0 +1,303 @@
+// Package cninfotest provides a local fake cninfo server for testing code
+// built on cninfo.Source without network access.
+//
+//	server := cninfotest.NewServer(&cninfotest.Dataset{...})
+//	defer server.Close()
+//	source := server.Source()
+package cninfotest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chamzzzzzz/financial/source/cninfo"
+)
+
+// Dataset is the data served by a Server.
+type Dataset struct {
+	// Stocks are served by the stock list of their Market, the A-share list
+	// if empty.
+	Stocks []*cninfo.Stock
+
+	// Announcements are served by the announcement query by category.
+	Announcements map[cninfo.Category][]*cninfo.Announcement
+
+	// Trades are the industries of the stocks by code, matched by the trade
+	// filter of the announcement query. Stocks without one match no trade.
+	Trades map[string]cninfo.Trade
+
+	// Dividends are served by the dividend endpoint by stock code.
+	Dividends map[string][]*cninfo.DividendRecord
+
+	// Adjuncts are the adjunct files served by AdjunctURL, with support for
+	// Range requests.
+	Adjuncts map[string][]byte
+}
+
+// Fault is a failure injected into the responses of a Server.
+type Fault struct {
+	// Delay delays the response.
+	Delay time.Duration
+
+	// StatusCode, if not zero, is answered instead of the data.
+	StatusCode int
+
+	// AntiBot answers the HTML page cninfo serves when throttling.
+	AntiBot bool
+
+	// Malformed answers truncated JSON.
+	Malformed bool
+
+	// IgnoreRange answers the whole file to a Range request.
+	IgnoreRange bool
+}
+
+// Server is a fake cninfo server.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	dataset  *Dataset
+	faults   []Fault
+	requests int
+}
+
+// NewServer starts a server serving dataset. The caller must Close it.
+func NewServer(dataset *Dataset) *Server {
+	s := &Server{dataset: dataset}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/new/data/", s.handleStockList)
+	mux.HandleFunc("/new/hisAnnouncement/query", s.handleQuery)
+	mux.HandleFunc("/data20/companyOverview/getCompanyHisDividend", s.handleDividend)
+	mux.HandleFunc("/", s.handleAdjunct)
+	s.Server = httptest.NewServer(s.inject(mux))
+	return s
+}
+
+// Source returns a source talking to s, without rate limit and with short
+// retry backoffs.
+func (s *Server) Source() *cninfo.Source {
+	return &cninfo.Source{
+		Client:    s.Client(),
+		BaseURL:   s.URL,
+		StaticURL: s.URL,
+		Limiter:   cninfo.NewRateLimiter(0, 1),
+		Retry:     &cninfo.RetryPolicy{MaxRetries: 3, MinBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond},
+	}
+}
+
+// Fail makes the next n requests fail with f.
+func (s *Server) Fail(n int, f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i < n; i++ {
+		s.faults = append(s.faults, f)
+	}
+}
+
+// Requests returns the number of requests served so far.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func (s *Server) inject(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests++
+		var f *Fault
+		if len(s.faults) > 0 {
+			f = &s.faults[0]
+			s.faults = s.faults[1:]
+		}
+		s.mu.Unlock()
+		if f == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if f.Delay > 0 {
+			select {
+			case <-time.After(f.Delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		switch {
+		case f.StatusCode != 0:
+			http.Error(w, http.StatusText(f.StatusCode), f.StatusCode)
+		case f.AntiBot:
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, "<html><head><title>访问频繁</title></head><body>您的访问过于频繁，请稍后再试。</body></html>")
+		case f.Malformed:
+			w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+			fmt.Fprint(w, `{"announcements":[{"secCode":"0000`)
+		case f.IgnoreRange:
+			r.Header.Del("Range")
+			next.ServeHTTP(w, r)
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	json.NewEncoder(w).Encode(v)
+}
+
+func (s *Server) handleStockList(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/new/data/")
+	if !strings.HasSuffix(name, "_stock.json") {
+		http.NotFound(w, r)
+		return
+	}
+	market := cninfo.Market(strings.TrimSuffix(name, "_stock.json"))
+	p := &cninfo.StockListResponse{StockList: []*cninfo.Stock{}}
+	for _, stock := range s.dataset.Stocks {
+		m := stock.Market
+		if m == "" {
+			m = cninfo.MarketAShare
+		}
+		if m == market {
+			v := *stock
+			v.Market = ""
+			p.StockList = append(p.StockList, &v)
+		}
+	}
+	writeJSON(w, p)
+}
+
+// maxPages is the number of pages cninfo lets a query page through.
+const maxPages = 100
+
+func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pageNum, _ := strconv.Atoi(r.Form.Get("pageNum"))
+	pageSize, _ := strconv.Atoi(r.Form.Get("pageSize"))
+	if pageNum < 1 {
+		pageNum = 1
+	}
+	if pageSize < 1 {
+		pageSize = 30
+	}
+	code := strings.Split(r.Form.Get("stock"), ",")[0]
+	categories := make(map[cninfo.Category]bool)
+	for _, c := range strings.Split(r.Form.Get("category"), ";") {
+		if c != "" {
+			categories[cninfo.Category(c)] = true
+		}
+	}
+	var plates []cninfo.Plate
+	for _, p := range strings.Split(r.Form.Get("plate"), ";") {
+		if p != "" {
+			plates = append(plates, cninfo.Plate(p))
+		}
+	}
+	trades := make(map[cninfo.Trade]bool)
+	for _, t := range strings.Split(r.Form.Get("trade"), ";") {
+		if t != "" {
+			trades[cninfo.Trade(t)] = true
+		}
+	}
+	keyword := r.Form.Get("searchkey")
+	var start, end time.Time
+	if se := strings.Split(r.Form.Get("seDate"), "~"); len(se) == 2 {
+		start, _ = cninfo.ParseDate(se[0])
+		end, _ = cninfo.ParseDate(se[1])
+	}
+
+	var matched []*cninfo.Announcement
+	for c, announcements := range s.dataset.Announcements {
+		if len(categories) > 0 && !categories[c] {
+			continue
+		}
+		for _, announcement := range announcements {
+			if code != "" && announcement.SecCode != code {
+				continue
+			}
+			if len(plates) > 0 && !onPlates(announcement.SecCode, plates) {
+				continue
+			}
+			if len(trades) > 0 && !trades[s.dataset.Trades[announcement.SecCode]] {
+				continue
+			}
+			if keyword != "" && !strings.Contains(announcement.AnnouncementTitle, keyword) {
+				continue
+			}
+			date := cninfo.Date(announcement.Time())
+			if !start.IsZero() && date.Before(start) || !end.IsZero() && date.After(end) {
+				continue
+			}
+			matched = append(matched, announcement)
+		}
+	}
+	asc := r.Form.Get("sortType") == cninfo.SortAsc
+	sort.SliceStable(matched, func(i, j int) bool {
+		if asc {
+			return matched[i].AnnouncementTime < matched[j].AnnouncementTime
+		}
+		return matched[i].AnnouncementTime > matched[j].AnnouncementTime
+	})
+
+	total := len(matched)
+	pages := (total + pageSize - 1) / pageSize
+	p := &cninfo.HisAnnouncementQueryResponse{
+		TotalAnnouncement: total,
+		TotalRecordNum:    total,
+		HasMore:           pageNum < pages,
+		Totalpages:        pages,
+	}
+	// Like cninfo, pages beyond maxPages are empty whatever the total.
+	if from := (pageNum - 1) * pageSize; from < total && pageNum <= maxPages {
+		to := from + pageSize
+		if to > total {
+			to = total
+		}
+		p.Announcements = matched[from:to]
+	}
+	writeJSON(w, p)
+}
+
+func onPlates(code string, plates []cninfo.Plate) bool {
+	for _, plate := range plates {
+		if plate.Contains(code) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) handleDividend(w http.ResponseWriter, r *http.Request) {
+	p := &cninfo.HisDividendResponse{Code: 200, Msg: "success"}
+	p.Data.ResultCode = "200"
+	p.Data.ResultMsg = "success"
+	p.Data.Records = s.dataset.Dividends[r.URL.Query().Get("scode")]
+	if p.Data.Records == nil {
+		p.Data.Records = []*cninfo.DividendRecord{}
+	}
+	p.Data.Total = len(p.Data.Records)
+	p.Data.Count = len(p.Data.Records)
+	writeJSON(w, p)
+}
+
+func (s *Server) handleAdjunct(w http.ResponseWriter, r *http.Request) {
+	b, ok := s.dataset.Adjuncts[strings.TrimPrefix(r.URL.Path, "/")]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(b))
+}
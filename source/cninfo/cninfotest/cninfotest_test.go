@@ -0,0 +1,114 @@
+package cninfotest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/chamzzzzzz/financial/source/cninfo"
+)
+
+func newDataset() *Dataset {
+	stock := &cninfo.Stock{Code: "000001", OrgID: "gssz0000001", Zwjc: "平安银行", Pinyin: "payh"}
+	d := &Dataset{
+		Stocks: []*cninfo.Stock{
+			stock,
+			{Code: "00700", OrgID: "gshk0000700", Zwjc: "腾讯控股", Pinyin: "txkg", Market: cninfo.MarketHK},
+		},
+		Announcements: make(map[cninfo.Category][]*cninfo.Announcement),
+		Dividends: map[string][]*cninfo.DividendRecord{
+			"000001": {{Period: "2022年报", Plan: "10派2.85元(含税)", PayDate: "2023-06-14"}},
+		},
+	}
+	for i := 0; i < 45; i++ {
+		t := time.Date(2020, 1, 1, 0, 0, 0, 0, cninfo.Location).AddDate(0, 0, i*20)
+		d.Announcements[cninfo.CategoryAnnualReport] = append(d.Announcements[cninfo.CategoryAnnualReport], &cninfo.Announcement{
+			SecCode:           stock.Code,
+			OrgID:             stock.OrgID,
+			AnnouncementID:    fmt.Sprint(1000 + i),
+			AnnouncementTitle: fmt.Sprintf("公告%d", i),
+			AnnouncementTime:  t.UnixMilli(),
+			AdjunctType:       "PDF",
+		})
+	}
+	d.Announcements[cninfo.CategorySemiAnnualReport] = []*cninfo.Announcement{{SecCode: stock.Code, AnnouncementID: "9", AnnouncementTime: time.Date(2020, 8, 20, 0, 0, 0, 0, cninfo.Location).UnixMilli()}}
+	return d
+}
+
+func TestServer(t *testing.T) {
+	server := NewServer(newDataset())
+	defer server.Close()
+	source := server.Source()
+
+	stocks, err := source.GetStockList()
+	if err != nil || len(stocks) != 1 || stocks[0].Code != "000001" {
+		t.Fatalf("got stocks %v, %v", stocks, err)
+	}
+	stocks, err = source.GetMarketStockList(cninfo.MarketHK)
+	if err != nil || len(stocks) != 1 || stocks[0].Market != cninfo.MarketHK {
+		t.Fatalf("got hk stocks %v, %v", stocks, err)
+	}
+
+	stock := &cninfo.Stock{Code: "000001", OrgID: "gssz0000001"}
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, cninfo.Location)
+	announcements, err := source.GetAnnualReportAnnoucements(stock, start, start.AddDate(0, 0, 20*40))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(announcements) != 41 {
+		t.Errorf("got %d announcements, want 41", len(announcements))
+	}
+
+	records, err := source.GetDividendRecords(stock)
+	if err != nil || len(records) != 1 {
+		t.Errorf("got records %v, %v", records, err)
+	}
+}
+
+func TestServerFaults(t *testing.T) {
+	server := NewServer(newDataset())
+	defer server.Close()
+	source := server.Source()
+	stock := &cninfo.Stock{Code: "000001"}
+
+	server.Fail(1, Fault{StatusCode: http.StatusServiceUnavailable})
+	server.Fail(1, Fault{AntiBot: true})
+	if _, err := source.GetDividendRecords(stock); err != nil {
+		t.Errorf("got error %v after transient faults", err)
+	}
+
+	server.Fail(1, Fault{Malformed: true})
+	var schemaErr *cninfo.SchemaError
+	if _, err := source.GetDividendRecords(stock); !errors.As(err, &schemaErr) {
+		t.Errorf("got error %v for malformed json, want SchemaError", err)
+	}
+
+	server.Fail(4, Fault{StatusCode: http.StatusBadGateway})
+	var statusErr *cninfo.StatusError
+	if _, err := source.GetDividendRecords(stock); !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadGateway {
+		t.Errorf("got error %v after exhausting retries, want status 502", err)
+	}
+
+	server.Fail(4, Fault{AntiBot: true})
+	if _, err := source.GetDividendRecords(stock); !errors.Is(err, cninfo.ErrAntiBot) || !errors.Is(err, cninfo.ErrThrottled) {
+		t.Errorf("got error %v after anti-bot pages, want ErrAntiBot", err)
+	}
+
+	requests := server.Requests()
+	server.Fail(1, Fault{StatusCode: http.StatusNotFound})
+	if _, err := source.GetDividendRecords(stock); !errors.Is(err, cninfo.ErrNotFound) {
+		t.Errorf("got error %v for status 404, want ErrNotFound", err)
+	}
+	if n := server.Requests() - requests; n != 1 {
+		t.Errorf("got %d requests for status 404, want 1 without retry", n)
+	}
+
+	source.Timeout = 10 * time.Millisecond
+	source.Retry = cninfo.NoRetry
+	server.Fail(1, Fault{Delay: time.Second})
+	if _, err := source.GetDividendRecords(stock); err == nil {
+		t.Error("got no error for slow response")
+	}
+}
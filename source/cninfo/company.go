@@ -0,0 +1,88 @@
+package cninfo
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Company is the basic information of a listed company as shown on the
+// cninfo company overview page.
+type Company struct {
+	Code                string `json:"ASECCODE"`
+	Name                string `json:"ASECNAME"`
+	FullName            string `json:"ORGNAME"`
+	EnglishName         string `json:"F001V"`
+	LegalRepresentative string `json:"F003V"`
+	RegisteredAddress   string `json:"F004V"`
+	OfficeAddress       string `json:"F005V"`
+	EstablishDate       string `json:"F010D"`
+	Website             string `json:"F011V"`
+	MainBusiness        string `json:"F015V"`
+	Industry            string `json:"F032V"`
+	ListingDate         string `json:"listingDate"`
+}
+
+// ListingTime returns the listing date, or the zero time if not set.
+func (c *Company) ListingTime() (time.Time, error) {
+	return ParseDate(c.ListingDate)
+}
+
+type CompanyIntroductionResponse struct {
+	Path string `json:"path"`
+	Code any    `json:"code"`
+	Msg  string `json:"msg"`
+	Data struct {
+		Total      int    `json:"total"`
+		Count      int    `json:"count"`
+		ResultMsg  string `json:"resultMsg"`
+		ResultCode string `json:"resultCode"`
+		Records    []struct {
+			BasicInformation   []*Company `json:"basicInformation"`
+			ListingInformation []struct {
+				ListingDate string `json:"F006D"`
+			} `json:"listingInformation"`
+		} `json:"records"`
+	} `json:"data"`
+}
+
+func (r *CompanyIntroductionResponse) GetCodeString() string {
+	return fmt.Sprintf("%v", r.Code)
+}
+
+func (s *Source) RequestCompanyIntroduction(stockCode string) (*CompanyIntroductionResponse, error) {
+	return s.RequestCompanyIntroductionContext(context.Background(), stockCode)
+}
+
+func (s *Source) RequestCompanyIntroductionContext(ctx context.Context, stockCode string) (*CompanyIntroductionResponse, error) {
+	p := &CompanyIntroductionResponse{}
+	if err := s.getData20(ctx, "/companyOverview/getCompanyIntroduction", stockCode, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *Source) GetCompany(stock *Stock) (*Company, error) {
+	return s.GetCompanyContext(context.Background(), stock)
+}
+
+func (s *Source) GetCompanyContext(ctx context.Context, stock *Stock) (*Company, error) {
+	p, err := s.RequestCompanyIntroductionContext(ctx, stock.Code)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCode(p.GetCodeString(), p.Msg); err != nil {
+		return nil, err
+	}
+	for _, record := range p.Data.Records {
+		if len(record.BasicInformation) == 0 {
+			continue
+		}
+		company := record.BasicInformation[0]
+		if len(record.ListingInformation) > 0 {
+			company.ListingDate = record.ListingInformation[0].ListingDate
+		}
+		return company, nil
+	}
+	return nil, fmt.Errorf("company of %s: %w", stock.Code, ErrNotFound)
+}
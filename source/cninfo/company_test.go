@@ -0,0 +1,30 @@
+package cninfo_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chamzzzzzz/financial/source/cninfo"
+	"github.com/chamzzzzzz/financial/source/cninfo/replay/replaytest"
+)
+
+func TestGetCompany(t *testing.T) {
+	source := replaytest.NewSource(t, filepath.Join("testdata", "company.json"))
+	company, err := source.GetCompany(&cninfo.Stock{Code: "000001"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if company.Code != "000001" || company.FullName != "平安银行股份有限公司" || company.Industry != "货币金融服务" || company.EstablishDate != "1987-12-22" {
+		t.Fatalf("got company %+v", company)
+	}
+	listed, err := company.ListingTime()
+	if err != nil || !listed.Equal(time.Date(1991, 4, 3, 0, 0, 0, 0, cninfo.Location)) {
+		t.Fatalf("got listing time %v, %v, want 1991-04-03", listed, err)
+	}
+
+	if _, err := source.GetCompany(&cninfo.Stock{Code: "000013"}); !errors.Is(err, cninfo.ErrNotFound) {
+		t.Fatalf("got error %v for a delisted stock, want not found", err)
+	}
+}
@@ -0,0 +1,126 @@
+package cninfo
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DividendPlan is the structured form of DividendRecord.Plan. Amounts are
+// given per Base shares, as written in the plan; use the PerShare methods to
+// compare plans with different bases.
+type DividendPlan struct {
+	Base         int
+	Cash         float64
+	CashAfterTax float64
+	Bonus        float64
+	Transfer     float64
+	Currency     string
+}
+
+func (p *DividendPlan) perShare(v float64) float64 {
+	if p.Base == 0 {
+		return 0
+	}
+	return v / float64(p.Base)
+}
+
+// CashPerShare returns the cash dividend per share before tax.
+func (p *DividendPlan) CashPerShare() float64 {
+	return p.perShare(p.Cash)
+}
+
+// CashAfterTaxPerShare returns the cash dividend per share after tax, or
+// zero if the plan does not state it.
+func (p *DividendPlan) CashAfterTaxPerShare() float64 {
+	return p.perShare(p.CashAfterTax)
+}
+
+// BonusPerShare returns the bonus shares (送股) per share.
+func (p *DividendPlan) BonusPerShare() float64 {
+	return p.perShare(p.Bonus)
+}
+
+// TransferPerShare returns the capitalization shares (转增) per share.
+func (p *DividendPlan) TransferPerShare() float64 {
+	return p.perShare(p.Transfer)
+}
+
+// IsEmpty reports whether the plan distributes nothing.
+func (p *DividendPlan) IsEmpty() bool {
+	return p.Cash == 0 && p.Bonus == 0 && p.Transfer == 0
+}
+
+var (
+	planBaseRegexp      = regexp.MustCompile(`^(?:每(\d*)股|(\d+)股?)`)
+	planPerShareRegexp  = regexp.MustCompile(`/股|每股`)
+	planCashRegexp      = regexp.MustCompile(`派(?:发现金红利|现金红利|现金|发|息)?(\d+(?:\.\d+)?)(港元|港币|美元|人民币|元|HKD|USD|RMB|CNY)`)
+	planCashFirstRegexp = regexp.MustCompile(`派(?:发现金红利|现金红利|现金|发|息)?(港元|港币|美元|人民币|HKD|USD|RMB|CNY)(\d+(?:\.\d+)?)`)
+	planAfterTaxRegexp  = regexp.MustCompile(`税后(?:每\d+股)?(?:实际)?(?:派)?(\d+(?:\.\d+)?)`)
+	planBonusRegexp     = regexp.MustCompile(`送(?:红股)?(\d+(?:\.\d+)?)股`)
+	planTransferRegexp  = regexp.MustCompile(`转增?(\d+(?:\.\d+)?)股`)
+	planReplacer        = strings.NewReplacer("（", "(", "）", ")", "，", ",", " ", "", "　", "")
+	planCurrencies      = map[string]string{
+		"元": "CNY", "人民币": "CNY", "RMB": "CNY", "CNY": "CNY",
+		"港元": "HKD", "港币": "HKD", "HKD": "HKD",
+		"美元": "USD", "USD": "USD",
+	}
+)
+
+// ParseDividendPlan parses plans such as "10派3.5元(含税)送2股转增3股" or
+// "10派2.1元(含税,扣税后1.89元)", and HK plans such as "每股派息HKD0.30" or
+// "派港元0.5/股". Plans like "不分配不转增" parse to an empty
+// plan.
+func ParseDividendPlan(s string) (*DividendPlan, error) {
+	s = planReplacer.Replace(s)
+	p := &DividendPlan{Base: 10, Currency: "CNY"}
+	if s == "" || s == "--" || strings.HasPrefix(s, "不分配") {
+		return p, nil
+	}
+
+	matched := false
+	if m := planBaseRegexp.FindStringSubmatch(s); m != nil {
+		// A bare "每股" is per one share.
+		base, err := 1, error(nil)
+		if n := m[1] + m[2]; n != "" {
+			base, err = strconv.Atoi(n)
+		}
+		if err != nil || base == 0 {
+			return nil, fmt.Errorf("invalid dividend plan base %q", s)
+		}
+		p.Base = base
+	} else if planPerShareRegexp.MatchString(s) {
+		p.Base = 1
+	}
+	if m := planCashRegexp.FindStringSubmatch(s); m != nil {
+		p.Cash, _ = strconv.ParseFloat(m[1], 64)
+		p.Currency = planCurrencies[m[2]]
+		matched = true
+	} else if m := planCashFirstRegexp.FindStringSubmatch(s); m != nil {
+		// HK plans may give the currency first, e.g. "派港元0.5/股".
+		p.Cash, _ = strconv.ParseFloat(m[2], 64)
+		p.Currency = planCurrencies[m[1]]
+		matched = true
+	}
+	if m := planAfterTaxRegexp.FindStringSubmatch(s); m != nil {
+		p.CashAfterTax, _ = strconv.ParseFloat(m[1], 64)
+	}
+	if m := planBonusRegexp.FindStringSubmatch(s); m != nil {
+		p.Bonus, _ = strconv.ParseFloat(m[1], 64)
+		matched = true
+	}
+	if m := planTransferRegexp.FindStringSubmatch(s); m != nil {
+		p.Transfer, _ = strconv.ParseFloat(m[1], 64)
+		matched = true
+	}
+	if !matched {
+		return nil, fmt.Errorf("unknown dividend plan %q", s)
+	}
+	return p, nil
+}
+
+// ParsePlan parses r.Plan with ParseDividendPlan.
+func (r *DividendRecord) ParsePlan() (*DividendPlan, error) {
+	return ParseDividendPlan(r.Plan)
+}
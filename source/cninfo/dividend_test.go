@@ -0,0 +1,59 @@
+package cninfo
+
+import (
+	"testing"
+)
+
+func TestParseDividendPlan(t *testing.T) {
+	tests := []struct {
+		plan string
+		want DividendPlan
+	}{
+		{"10派3.5元(含税)送2股转增3股", DividendPlan{Base: 10, Cash: 3.5, Bonus: 2, Transfer: 3, Currency: "CNY"}},
+		{"10派2.28元(含税,扣税后2.052元)", DividendPlan{Base: 10, Cash: 2.28, CashAfterTax: 2.052, Currency: "CNY"}},
+		{"10派1.5元（含税）（扣税后1.35元）", DividendPlan{Base: 10, Cash: 1.5, CashAfterTax: 1.35, Currency: "CNY"}},
+		{"10转增5股", DividendPlan{Base: 10, Transfer: 5, Currency: "CNY"}},
+		{"每10股派发现金红利3.5元", DividendPlan{Base: 10, Cash: 3.5, Currency: "CNY"}},
+		{"每股派0.5元", DividendPlan{Base: 1, Cash: 0.5, Currency: "CNY"}},
+		{"每股派发现金红利0.25港元(含税)", DividendPlan{Base: 1, Cash: 0.25, Currency: "HKD"}},
+		{"10送红股1股", DividendPlan{Base: 10, Bonus: 1, Currency: "CNY"}},
+		{"10派4.92港元(含税)", DividendPlan{Base: 10, Cash: 4.92, Currency: "HKD"}},
+		{"10派0.5美元(含税)", DividendPlan{Base: 10, Cash: 0.5, Currency: "USD"}},
+		{"不分配不转增", DividendPlan{Base: 10, Currency: "CNY"}},
+		{"派港元0.5/股", DividendPlan{Base: 1, Cash: 0.5, Currency: "HKD"}},
+		{"每股派息 HKD 0.30", DividendPlan{Base: 1, Cash: 0.3, Currency: "HKD"}},
+		{"每股派息USD0.12", DividendPlan{Base: 1, Cash: 0.12, Currency: "USD"}},
+		{"派0.8港元/股", DividendPlan{Base: 1, Cash: 0.8, Currency: "HKD"}},
+		{"每股派人民币0.2元", DividendPlan{Base: 1, Cash: 0.2, Currency: "CNY"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseDividendPlan(tt.plan)
+		if err != nil {
+			t.Errorf("ParseDividendPlan(%q) error: %v", tt.plan, err)
+			continue
+		}
+		if *got != tt.want {
+			t.Errorf("ParseDividendPlan(%q) = %+v, want %+v", tt.plan, *got, tt.want)
+		}
+	}
+
+	if _, err := ParseDividendPlan("方案待定"); err == nil {
+		t.Error("ParseDividendPlan(\"方案待定\") got no error")
+	}
+}
+
+func TestDividendPlanPerShare(t *testing.T) {
+	p := &DividendPlan{Base: 10, Cash: 3.5, CashAfterTax: 3.15, Bonus: 2, Transfer: 3}
+	if p.CashPerShare() != 0.35 || p.CashAfterTaxPerShare() != 0.315 || p.BonusPerShare() != 0.2 || p.TransferPerShare() != 0.3 {
+		t.Errorf("got per share %g, %g, %g, %g, want 0.35, 0.315, 0.2, 0.3", p.CashPerShare(), p.CashAfterTaxPerShare(), p.BonusPerShare(), p.TransferPerShare())
+	}
+	if p.IsEmpty() {
+		t.Error("got plan empty")
+	}
+	if p := (&DividendPlan{Cash: 1}); p.CashPerShare() != 0 {
+		t.Errorf("got %g per share without base, want 0", p.CashPerShare())
+	}
+	if p := (&DividendPlan{Base: 10}); !p.IsEmpty() {
+		t.Error("got plan without distribution not empty")
+	}
+}
@@ -0,0 +1,212 @@
+package cninfo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var pdfMagic = []byte("%PDF-")
+
+type writeError struct {
+	err error
+}
+
+func (e *writeError) Error() string {
+	return e.err.Error()
+}
+
+// progressWriter counts the bytes written to w, keeps the head of the file
+// for the magic check and reports progress.
+type progressWriter struct {
+	w        io.Writer
+	written  int64
+	total    int64
+	head     []byte
+	progress func(written, total int64)
+
+	// truncate, if set, empties w so that a whole file sent instead of the
+	// range asked for is written from the start.
+	truncate func() error
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	if n := len(pdfMagic) - len(p.head); n > 0 {
+		if n > len(b) {
+			n = len(b)
+		}
+		p.head = append(p.head, b[:n]...)
+	}
+	n, err := p.w.Write(b)
+	p.written += int64(n)
+	if p.progress != nil {
+		p.progress(p.written, p.total)
+	}
+	if err != nil {
+		return n, &writeError{err: err}
+	}
+	return n, nil
+}
+
+// DownloadAdjunct streams the adjunct file of announcement to w. Interrupted
+// transfers are resumed with Range requests under the retry policy of s.
+// The result is checked against AdjunctSize and, for PDF adjuncts, the PDF
+// magic.
+func (s *Source) DownloadAdjunct(ctx context.Context, announcement *Announcement, w io.Writer) error {
+	return s.downloadAdjunct(ctx, announcement, &progressWriter{w: w, total: -1})
+}
+
+// DownloadAdjunctFile downloads the adjunct file of announcement to file. The
+// data is written to file+".part" first, so a later call resumes where an
+// interrupted one stopped, and renamed to file once verified.
+func (s *Source) DownloadAdjunctFile(ctx context.Context, announcement *Announcement, file string) error {
+	part := file + ".part"
+	f, err := os.OpenFile(part, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	pw := &progressWriter{w: f, written: fi.Size(), total: -1, truncate: func() error { return f.Truncate(0) }}
+	if pw.written > 0 {
+		head := make([]byte, len(pdfMagic))
+		n, err := f.ReadAt(head, 0)
+		if err != nil && err != io.EOF {
+			f.Close()
+			return err
+		}
+		pw.head = head[:n]
+	}
+	err = s.downloadAdjunct(ctx, announcement, pw)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		// A corrupt part file would be resumed forever, start over.
+		var ve *verifyError
+		if errors.As(err, &ve) {
+			os.Remove(part)
+		}
+		return err
+	}
+	return os.Rename(part, file)
+}
+
+type verifyError struct {
+	msg string
+}
+
+func (e *verifyError) Error() string {
+	return e.msg
+}
+
+func (s *Source) downloadAdjunct(ctx context.Context, announcement *Announcement, pw *progressWriter) error {
+	if s.DownloadProgress != nil {
+		pw.progress = func(written, total int64) {
+			s.DownloadProgress(announcement, written, total)
+		}
+	}
+	url := s.AdjunctURL(announcement)
+	err := s.retry().Do(ctx, func() error {
+		return s.fetchAdjunct(ctx, url, pw)
+	})
+	if err != nil {
+		return err
+	}
+
+	if pw.total >= 0 && pw.written != pw.total {
+		return &verifyError{msg: fmt.Sprintf("adjunct size mismatch %s, got %d of %d bytes", url, pw.written, pw.total)}
+	}
+	// AdjunctSize is given in KB.
+	if announcement.AdjunctSize > 0 {
+		kb := (pw.written + 1023) / 1024
+		if d := kb - int64(announcement.AdjunctSize); d < -1 || d > 1 {
+			return &verifyError{msg: fmt.Sprintf("adjunct size mismatch %s, got %dKB, want %dKB", url, kb, announcement.AdjunctSize)}
+		}
+	}
+	if strings.EqualFold(announcement.AdjunctType, "PDF") || strings.HasSuffix(strings.ToUpper(url), ".PDF") {
+		if !bytes.Equal(pw.head, pdfMagic) {
+			return &verifyError{msg: fmt.Sprintf("adjunct %s is not a pdf file", url)}
+		}
+	}
+	return nil
+}
+
+func (s *Source) fetchAdjunct(ctx context.Context, url string, pw *progressWriter) error {
+	if err := s.limiter().Wait(ctx); err != nil {
+		return err
+	}
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+	req, err := s.newRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	if pw.written > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", pw.written))
+	}
+	resp, err := s.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if resp.ContentLength >= 0 {
+			pw.total = resp.ContentLength
+		}
+		// The server ignored the range, start over if we can, else skip
+		// what we already have.
+		if pw.written > 0 && pw.truncate != nil {
+			if err := pw.truncate(); err != nil {
+				return &writeError{err: err}
+			}
+			pw.written, pw.head = 0, nil
+		} else if pw.written > 0 {
+			if _, err := io.CopyN(io.Discard, resp.Body, pw.written); err != nil {
+				return err
+			}
+		}
+	case http.StatusPartialContent:
+		pw.total = contentRangeTotal(resp.Header.Get("Content-Range"))
+	case http.StatusRequestedRangeNotSatisfiable:
+		if pw.written > 0 {
+			if total := contentRangeTotal(resp.Header.Get("Content-Range")); total >= 0 {
+				pw.total = total
+			}
+			return nil
+		}
+		return &StatusError{StatusCode: resp.StatusCode}
+	default:
+		return &StatusError{StatusCode: resp.StatusCode}
+	}
+	_, err = io.Copy(pw, resp.Body)
+	return err
+}
+
+// contentRangeTotal returns the complete length of a "bytes a-b/total"
+// header, or -1 if unknown.
+func contentRangeTotal(s string) int64 {
+	i := strings.LastIndex(s, "/")
+	if i < 0 {
+		return -1
+	}
+	total, err := strconv.ParseInt(s[i+1:], 10, 64)
+	if err != nil {
+		return -1
+	}
+	return total
+}
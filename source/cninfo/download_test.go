@@ -0,0 +1,127 @@
+package cninfo_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chamzzzzzz/financial/source/cninfo"
+	"github.com/chamzzzzzz/financial/source/cninfo/cninfotest"
+)
+
+func newAdjunctServer(t *testing.T) (*cninfotest.Server, []byte) {
+	t.Helper()
+	pdf := append([]byte("%PDF-1.7\n"), bytes.Repeat([]byte("0123456789"), 300)...)
+	server := cninfotest.NewServer(&cninfotest.Dataset{
+		Adjuncts: map[string][]byte{
+			"finalpage/2023-04-20/1.PDF": pdf,
+			"finalpage/2023-04-20/2.PDF": []byte("<html>not found</html>"),
+		},
+	})
+	t.Cleanup(server.Close)
+	return server, pdf
+}
+
+func TestDownloadAdjunctFile(t *testing.T) {
+	server, pdf := newAdjunctServer(t)
+	announcement := &cninfo.Announcement{AdjunctURL: "finalpage/2023-04-20/1.PDF", AdjunctType: "PDF", AdjunctSize: 3}
+	tests := []struct {
+		name  string
+		part  []byte
+		fault *cninfotest.Fault
+	}{
+		{name: "new"},
+		{name: "resume", part: pdf[:1000]},
+		{name: "ignore range", part: []byte("%PDF-stale data"), fault: &cninfotest.Fault{IgnoreRange: true}},
+		{name: "complete", part: pdf},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "1.pdf")
+			if tt.part != nil {
+				if err := os.WriteFile(file+".part", tt.part, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.fault != nil {
+				server.Fail(1, *tt.fault)
+			}
+			if err := server.Source().DownloadAdjunctFile(context.Background(), announcement, file); err != nil {
+				t.Fatal(err)
+			}
+			b, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(b, pdf) {
+				t.Fatalf("got %d bytes, want the %d bytes of the adjunct", len(b), len(pdf))
+			}
+			if _, err := os.Stat(file + ".part"); !os.IsNotExist(err) {
+				t.Fatalf("got part file left, err=%v", err)
+			}
+		})
+	}
+}
+
+func TestDownloadAdjunctFileVerify(t *testing.T) {
+	server, _ := newAdjunctServer(t)
+	tests := []struct {
+		name         string
+		announcement *cninfo.Announcement
+		want         string
+	}{
+		{"size", &cninfo.Announcement{AdjunctURL: "finalpage/2023-04-20/1.PDF", AdjunctType: "PDF", AdjunctSize: 10}, "size mismatch"},
+		{"magic", &cninfo.Announcement{AdjunctURL: "finalpage/2023-04-20/2.PDF", AdjunctType: "PDF"}, "not a pdf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "1.pdf")
+			err := server.Source().DownloadAdjunctFile(context.Background(), tt.announcement, file)
+			var ve *cninfo.VerifyError
+			if !errors.As(err, &ve) || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("got error %v, want a %s verify error", err, tt.want)
+			}
+			for _, name := range []string{file, file + ".part"} {
+				if _, err := os.Stat(name); !os.IsNotExist(err) {
+					t.Errorf("got %s left, err=%v", name, err)
+				}
+			}
+		})
+	}
+}
+
+func TestDownloadAdjunctProgress(t *testing.T) {
+	server, pdf := newAdjunctServer(t)
+	s := server.Source()
+	var calls int
+	var written, total int64
+	s.DownloadProgress = func(announcement *cninfo.Announcement, w, t int64) {
+		calls++
+		written, total = w, t
+	}
+	var buf bytes.Buffer
+	if err := s.DownloadAdjunct(context.Background(), &cninfo.Announcement{AdjunctURL: "finalpage/2023-04-20/1.PDF", AdjunctType: "PDF"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if calls == 0 || written != int64(len(pdf)) || total != int64(len(pdf)) || buf.Len() != len(pdf) {
+		t.Fatalf("got %d calls ending at %d of %d bytes, want %d bytes", calls, written, total, len(pdf))
+	}
+}
+
+func TestContentRangeTotal(t *testing.T) {
+	tests := map[string]int64{
+		"bytes 0-99/1000": 1000,
+		"bytes */1000":    1000,
+		"bytes 0-99/*":    -1,
+		"":                -1,
+	}
+	for s, want := range tests {
+		if got := cninfo.ContentRangeTotal(s); got != want {
+			t.Errorf("contentRangeTotal(%q) = %d, want %d", s, got, want)
+		}
+	}
+}
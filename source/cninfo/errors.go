@@ -0,0 +1,102 @@
+package cninfo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+)
+
+var (
+	// ErrNotFound is matched by the errors of requests for things cninfo
+	// does not have, e.g. a 404 response.
+	ErrNotFound = errors.New("cninfo: not found")
+
+	// ErrThrottled is matched by the errors of requests cninfo refused
+	// because of their rate. They are worth retrying later.
+	ErrThrottled = errors.New("cninfo: throttled")
+
+	// ErrAntiBot is returned when cninfo answers with its HTML anti-bot
+	// page instead of data. It matches ErrThrottled.
+	ErrAntiBot = fmt.Errorf("cninfo: anti-bot page: %w", ErrThrottled)
+)
+
+// StatusError is returned for responses with an unexpected HTTP status. It
+// matches ErrNotFound for 404 and 410 and ErrThrottled for 429.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("status code:%d", e.StatusCode)
+}
+
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound || e.StatusCode == http.StatusGone
+	case ErrThrottled:
+		return e.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// APIError is returned when a data20 response carries a code other than
+// 200.
+type APIError struct {
+	Code string
+	Msg  string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("code: %s, msg: %s", e.Code, e.Msg)
+}
+
+// checkCode returns an APIError unless code is 200.
+func checkCode(code, msg string) error {
+	if code != "200" {
+		return &APIError{Code: code, Msg: msg}
+	}
+	return nil
+}
+
+// SchemaError is returned when a response is not the JSON expected, e.g.
+// because cninfo changed its schema.
+type SchemaError struct {
+	URL string
+	Err error
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("unexpected response of %s: %v", e.URL, e.Err)
+}
+
+func (e *SchemaError) Unwrap() error {
+	return e.Err
+}
+
+// isTransient reports whether a request failing with err is worth sending
+// again: timeouts, dropped connections, throttling and server errors.
+// Requests cancelled by the caller are never retried, callers still check
+// their context as a deadline of it is a timeout too.
+func isTransient(err error) bool {
+	var we *writeError
+	if errors.As(err, &we) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.StatusCode == http.StatusTooManyRequests || se.StatusCode >= 500
+	}
+	if errors.Is(err, ErrThrottled) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
@@ -0,0 +1,44 @@
+package cninfo
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestIsTransient(t *testing.T) {
+	urlError := func(err error) error {
+		return &url.Error{Op: "Get", URL: "http://www.cninfo.com.cn", Err: err}
+	}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"timeout", urlError(context.DeadlineExceeded), true},
+		{"dial timeout", urlError(&net.OpError{Op: "dial", Err: os.ErrDeadlineExceeded}), true},
+		{"connection reset", urlError(&net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}), true},
+		{"connection refused", urlError(&net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}), true},
+		{"unexpected eof", urlError(io.ErrUnexpectedEOF), true},
+		{"too many requests", &StatusError{StatusCode: http.StatusTooManyRequests}, true},
+		{"bad gateway", &StatusError{StatusCode: http.StatusBadGateway}, true},
+		{"anti-bot", ErrAntiBot, true},
+		{"not found", &StatusError{StatusCode: http.StatusNotFound}, false},
+		{"canceled", urlError(context.Canceled), false},
+		{"unsupported scheme", urlError(errors.New(`unsupported protocol scheme "ftp"`)), false},
+		{"unknown authority", urlError(x509.UnknownAuthorityError{}), false},
+		{"write", &writeError{err: io.ErrUnexpectedEOF}, false},
+	}
+	for _, tt := range tests {
+		if got := isTransient(tt.err); got != tt.want {
+			t.Errorf("isTransient(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,7 @@
+package cninfo
+
+var ErrIncomplete = errIncomplete
+
+type VerifyError = verifyError
+
+var ContentRangeTotal = contentRangeTotal
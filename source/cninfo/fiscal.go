@@ -0,0 +1,47 @@
+package cninfo
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	titleYearRegexp   = regexp.MustCompile(`((?:19|20)\d{2}|[〇零一二三四五六七八九]{4})\s*年`)
+	englishYearRegexp = regexp.MustCompile(`\b((?:19|20)\d{2})\b`)
+	chineseDigits     = strings.NewReplacer("〇", "0", "零", "0", "一", "1", "二", "2", "三", "3", "四", "4", "五", "5", "六", "6", "七", "7", "八", "8", "九", "9")
+)
+
+// TitleFiscalYear returns the fiscal year a periodic report title is about,
+// e.g. 2022 for "2022年年度报告", "二〇二二年年度报告" and "2022 Annual
+// Report". It reports false if the title has no year.
+func TitleFiscalYear(title string) (int, bool) {
+	var s string
+	if m := titleYearRegexp.FindStringSubmatch(title); m != nil {
+		s = chineseDigits.Replace(m[1])
+	} else if m := englishYearRegexp.FindStringSubmatch(title); m != nil {
+		s = m[1]
+	} else {
+		return 0, false
+	}
+	year, err := strconv.Atoi(s)
+	if err != nil || year < 1990 || year > 2100 {
+		return 0, false
+	}
+	return year, true
+}
+
+// FiscalYear returns the fiscal year of a periodic report announcement of
+// category. The year in the title is used if there is one. Otherwise it is
+// guessed from the publish time: annual reports are published the year
+// after, the others in the same year.
+func (a *Announcement) FiscalYear(category Category) int {
+	if year, ok := TitleFiscalYear(a.AnnouncementTitle); ok {
+		return year
+	}
+	year := a.Time().Year()
+	if category == CategoryAnnualReport {
+		year--
+	}
+	return year
+}
@@ -0,0 +1,28 @@
+package cninfo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFiscalYear(t *testing.T) {
+	published := &Announcement{AnnouncementTime: time.Date(2023, 4, 28, 0, 0, 0, 0, Location).UnixMilli()}
+	tests := []struct {
+		title    string
+		category Category
+		want     int
+	}{
+		{"2022年年度报告", CategoryAnnualReport, 2022},
+		{"平安银行：2019年年度报告（更正后）", CategoryAnnualReport, 2019},
+		{"二〇二二年年度报告", CategoryAnnualReport, 2022},
+		{"2022 Annual Report", CategoryAnnualReport, 2022},
+		{"年度报告", CategoryAnnualReport, 2022},
+		{"第一季度报告", CategoryFirstQuarterReport, 2023},
+	}
+	for _, tt := range tests {
+		published.AnnouncementTitle = tt.title
+		if got := published.FiscalYear(tt.category); got != tt.want {
+			t.Errorf("FiscalYear(%q) = %d, want %d", tt.title, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package cninfo
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket that allows one request every interval with
+// bursts of up to burst requests. It is safe for concurrent use and is meant
+// to be shared by every Source talking to the same host.
+type RateLimiter struct {
+	every  time.Duration
+	burst  int
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// DefaultRateLimiter is used by every Source without a Limiter.
+var DefaultRateLimiter = NewRateLimiter(200*time.Millisecond, 5)
+
+// NewRateLimiter returns a limiter allowing one request every interval. A
+// non-positive every disables limiting.
+func NewRateLimiter(every time.Duration, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{every: every, burst: burst, tokens: float64(burst)}
+}
+
+// Wait blocks until a request is allowed or ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l.every <= 0 {
+		return ctx.Err()
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if !l.last.IsZero() {
+		l.tokens += float64(now.Sub(l.last)) / float64(l.every)
+		if l.tokens > float64(l.burst) {
+			l.tokens = float64(l.burst)
+		}
+	}
+	l.last = now
+	l.tokens--
+	wait := time.Duration(-l.tokens * float64(l.every))
+	l.mu.Unlock()
+	if wait <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// RetryPolicy decides how often and how long to wait before a failed request
+// is sent again. Only transient failures are retried: transport errors,
+// 429 and 5xx responses, and the HTML pages cninfo serves when throttling.
+type RetryPolicy struct {
+	MaxRetries int
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+var (
+	// DefaultRetryPolicy is used by every Source without a Retry.
+	DefaultRetryPolicy = &RetryPolicy{MaxRetries: 3, MinBackoff: time.Second, MaxBackoff: 30 * time.Second}
+
+	// NoRetry disables retrying.
+	NoRetry = &RetryPolicy{}
+)
+
+var (
+	jitterMu   sync.Mutex
+	jitterRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// Backoff returns the delay before retry number attempt, counting from zero.
+// The delay doubles with each attempt up to MaxBackoff, and a random jitter
+// of up to half of it is subtracted so concurrent clients spread out, but
+// never below MinBackoff.
+func (p *RetryPolicy) Backoff(attempt int) time.Duration {
+	d := p.MinBackoff
+	for i := 0; i < attempt && d > 0 && d <= math.MaxInt64/2 && (p.MaxBackoff <= 0 || d < p.MaxBackoff); i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	low := d / 2
+	if low < p.MinBackoff {
+		low = p.MinBackoff
+	}
+	if d <= low {
+		return d
+	}
+	jitterMu.Lock()
+	j := time.Duration(jitterRand.Int63n(int64(d-low) + 1))
+	jitterMu.Unlock()
+	return low + j
+}
+
+// Do calls fn until it succeeds or fails with an error that is not
+// transient, at most MaxRetries more times, sleeping Backoff in between. It
+// returns the last error of fn, or the error of ctx if it is done while
+// sleeping.
+func (p *RetryPolicy) Do(ctx context.Context, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.MaxRetries || ctx.Err() != nil || !isTransient(err) {
+			return err
+		}
+		if err := sleep(ctx, p.Backoff(attempt)); err != nil {
+			return err
+		}
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
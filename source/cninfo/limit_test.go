@@ -0,0 +1,88 @@
+package cninfo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	policies := []*RetryPolicy{
+		DefaultRetryPolicy,
+		{MinBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond},
+		{MinBackoff: time.Second},
+	}
+	for _, p := range policies {
+		for _, attempt := range []int{0, 1, 2, 3, 5, 10, 62, 63, 64, 100, 1 << 20} {
+			d := p.Backoff(attempt)
+			if d < p.MinBackoff || p.MaxBackoff > 0 && d > p.MaxBackoff {
+				t.Errorf("%+v: Backoff(%d) = %s, want within [%s, %s]", p, attempt, d, p.MinBackoff, p.MaxBackoff)
+			}
+		}
+	}
+	if d := DefaultRetryPolicy.Backoff(100); d < DefaultRetryPolicy.MaxBackoff/2 {
+		t.Errorf("got Backoff(100) = %s, want at least half of MaxBackoff", d)
+	}
+	if d := NoRetry.Backoff(3); d != 0 {
+		t.Errorf("got NoRetry.Backoff(3) = %s, want 0", d)
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	l := NewRateLimiter(time.Hour, 1)
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := l.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want the deadline of ctx", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("got Wait returning after %s, want it to return with ctx", time.Since(start))
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if err := l.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want ctx canceled", err)
+	}
+	if err := NewRateLimiter(0, 1).Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v from an unlimited limiter, want ctx canceled", err)
+	}
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	p := &RetryPolicy{MaxRetries: 2, MinBackoff: time.Millisecond}
+	calls := 0
+	err := p.Do(context.Background(), func() error {
+		calls++
+		return &StatusError{StatusCode: 503}
+	})
+	if calls != 3 || err == nil {
+		t.Fatalf("got %d calls, %v, want 3 calls and the last error", calls, err)
+	}
+
+	calls = 0
+	err = p.Do(context.Background(), func() error {
+		calls++
+		return &StatusError{StatusCode: 404}
+	})
+	if calls != 1 || err == nil {
+		t.Fatalf("got %d calls, %v, want a permanent error not retried", calls, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	calls = 0
+	err = (&RetryPolicy{MaxRetries: 5, MinBackoff: time.Hour}).Do(ctx, func() error {
+		calls++
+		cancel()
+		return ErrThrottled
+	})
+	if calls != 1 || !errors.Is(err, ErrThrottled) {
+		t.Fatalf("got %d calls, %v, want to stop once ctx is done", calls, err)
+	}
+}
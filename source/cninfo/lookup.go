@@ -0,0 +1,208 @@
+package cninfo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// StockIndex looks stocks up by code, pinyin abbreviation and name. It is
+// built once from a stock list and safe for concurrent reads.
+type StockIndex struct {
+	stocks []*Stock
+	codes  map[string]*Stock
+}
+
+// NewStockIndex indexes stocks. When a code is listed twice, e.g. in two
+// markets, the exact code lookup returns the first one.
+func NewStockIndex(stocks []*Stock) *StockIndex {
+	x := &StockIndex{stocks: stocks, codes: make(map[string]*Stock, len(stocks))}
+	for _, stock := range stocks {
+		if _, ok := x.codes[stock.Code]; !ok {
+			x.codes[stock.Code] = stock
+		}
+	}
+	return x
+}
+
+// Len returns the number of indexed stocks.
+func (x *StockIndex) Len() int {
+	return len(x.stocks)
+}
+
+// Code returns the stock of code, or nil.
+func (x *StockIndex) Code(code string) *Stock {
+	return x.codes[code]
+}
+
+// PinyinPrefix returns the stocks whose pinyin abbreviation starts with
+// prefix, case insensitive.
+func (x *StockIndex) PinyinPrefix(prefix string) []*Stock {
+	prefix = strings.ToLower(prefix)
+	var stocks []*Stock
+	for _, stock := range x.stocks {
+		if prefix != "" && strings.HasPrefix(strings.ToLower(stock.Pinyin), prefix) {
+			stocks = append(stocks, stock)
+		}
+	}
+	return stocks
+}
+
+// NameContains returns the stocks whose name contains s.
+func (x *StockIndex) NameContains(s string) []*Stock {
+	var stocks []*Stock
+	for _, stock := range x.stocks {
+		if s != "" && strings.Contains(stock.Zwjc, s) {
+			stocks = append(stocks, stock)
+		}
+	}
+	return stocks
+}
+
+// StockMatch is a stock found by StockIndex.Search with its score, higher
+// is better.
+type StockMatch struct {
+	Stock *Stock
+	Score int
+}
+
+// Scores of StockIndex.Search. Fuzzy matches score below ScoreSubstring.
+const (
+	ScoreCode         = 100
+	ScoreName         = 95
+	ScorePinyin       = 90
+	ScoreCodePrefix   = 80
+	ScorePinyinPrefix = 70
+	ScoreNamePrefix   = 65
+	ScoreSubstring    = 60
+)
+
+// score returns how well query matches stock, 0 if it does not.
+func score(stock *Stock, query string) int {
+	pinyin := strings.ToLower(stock.Pinyin)
+	lower := strings.ToLower(query)
+	switch {
+	case stock.Code == query:
+		return ScoreCode
+	case stock.Zwjc == query:
+		return ScoreName
+	case pinyin == lower:
+		return ScorePinyin
+	case strings.HasPrefix(stock.Code, query):
+		return ScoreCodePrefix
+	case strings.HasPrefix(pinyin, lower):
+		return ScorePinyinPrefix
+	case strings.HasPrefix(stock.Zwjc, query):
+		return ScoreNamePrefix
+	case strings.Contains(stock.Zwjc, query) || strings.Contains(pinyin, lower):
+		return ScoreSubstring
+	}
+	if s := subsequenceScore(stock.Zwjc, query); s > 0 {
+		return s
+	}
+	return subsequenceScore(pinyin, lower)
+}
+
+// subsequenceScore scores a match of the runes of query appearing in order
+// in s, e.g. "平银" in "平安银行". Tighter matches score higher, up to 50.
+func subsequenceScore(s, query string) int {
+	n := utf8.RuneCountInString(query)
+	if n == 0 {
+		return 0
+	}
+	q := []rune(query)
+	i, first, last := 0, -1, -1
+	for j, r := range []rune(s) {
+		if r != q[i] {
+			continue
+		}
+		if first < 0 {
+			first = j
+		}
+		last = j
+		if i++; i == n {
+			break
+		}
+	}
+	if i < n {
+		return 0
+	}
+	gaps := last - first + 1 - n
+	return 50 - 10*gaps/n - first
+}
+
+// Search returns the stocks matching query best first, ranked by exact
+// code, name and pinyin, then code, pinyin and name prefix, then substring
+// and in-order fuzzy matches. A non-positive limit returns every match.
+func (x *StockIndex) Search(query string, limit int) []*StockMatch {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil
+	}
+	var matches []*StockMatch
+	for _, stock := range x.stocks {
+		if s := score(stock, query); s > 0 {
+			matches = append(matches, &StockMatch{Stock: stock, Score: s})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Stock.Code < matches[j].Stock.Code
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// resolvable reports whether query names stock plainly enough for Resolve:
+// by exact code or name, a pinyin prefix or part of the name.
+func resolvable(stock *Stock, query string) bool {
+	return stock.Code == query || stock.Zwjc == query ||
+		strings.HasPrefix(strings.ToLower(stock.Pinyin), strings.ToLower(query)) ||
+		strings.Contains(stock.Zwjc, query)
+}
+
+// Resolve returns the one stock query refers to: the best of the stocks of
+// an exact code or name, a pinyin prefix or a part of the name. It fails
+// when nothing or several stocks match equally well, and lists the code
+// prefix and fuzzy matches as candidates instead of picking one of them.
+func (x *StockIndex) Resolve(query string) (*Stock, error) {
+	query = strings.TrimSpace(query)
+	if stock := x.Code(query); stock != nil {
+		return stock, nil
+	}
+	all := x.Search(query, 0)
+	var matches []*StockMatch
+	for _, m := range all {
+		if resolvable(m.Stock, query) {
+			matches = append(matches, m)
+		}
+	}
+	if len(matches) == 0 {
+		if len(all) == 0 {
+			return nil, fmt.Errorf("stock %q not found", query)
+		}
+		return nil, fmt.Errorf("stock %q not found, did you mean: %s", query, candidates(all, 0))
+	}
+	if len(matches) > 1 && matches[1].Score == matches[0].Score {
+		return nil, fmt.Errorf("stock %q is ambiguous: %s", query, candidates(matches, matches[0].Score))
+	}
+	return matches[0].Stock, nil
+}
+
+// candidates formats the first few matches, only the ones of score if it
+// is positive.
+func candidates(matches []*StockMatch, score int) string {
+	var s []string
+	for _, m := range matches {
+		if score > 0 && m.Score != score || len(s) == 5 {
+			break
+		}
+		s = append(s, m.Stock.Code+" "+m.Stock.Zwjc)
+	}
+	return strings.Join(s, ", ")
+}
@@ -0,0 +1,56 @@
+package cninfo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStockIndex(t *testing.T) {
+	x := NewStockIndex([]*Stock{
+		{Code: "000001", Pinyin: "payh", Zwjc: "平安银行"},
+		{Code: "000002", Pinyin: "wka", Zwjc: "万科A"},
+		{Code: "600000", Pinyin: "pfyh", Zwjc: "浦发银行"},
+		{Code: "601318", Pinyin: "zgpa", Zwjc: "中国平安"},
+	})
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"000001", "000001"},
+		{"pfyh", "600000"},
+		{"PAYH", "000001"},
+		{"平安银行", "000001"},
+		{"万科", "000002"},
+		{"中国", "601318"},
+	}
+	for _, tt := range tests {
+		stock, err := x.Resolve(tt.query)
+		if err != nil {
+			t.Errorf("Resolve(%q) error: %v", tt.query, err)
+			continue
+		}
+		if stock.Code != tt.want {
+			t.Errorf("Resolve(%q) = %s, want %s", tt.query, stock.Code, tt.want)
+		}
+	}
+
+	for _, query := range []string{"银行", "xyz", "p"} {
+		if stock, err := x.Resolve(query); err == nil {
+			t.Errorf("Resolve(%q) = %s, want error", query, stock.Code)
+		}
+	}
+	for _, query := range []string{"浦银", "6013"} {
+		if _, err := x.Resolve(query); err == nil || !strings.Contains(err.Error(), "did you mean") {
+			t.Errorf("Resolve(%q) error %v, want the fuzzy candidates", query, err)
+		}
+	}
+	if got := x.PinyinPrefix("p"); len(got) != 2 {
+		t.Errorf("PinyinPrefix(\"p\") got %d stocks, want 2", len(got))
+	}
+	if got := x.NameContains("平安"); len(got) != 2 {
+		t.Errorf("NameContains(\"平安\") got %d stocks, want 2", len(got))
+	}
+	if got := x.Search("平安", 0); len(got) != 2 || got[0].Stock.Code != "000001" {
+		t.Errorf("Search(\"平安\") got %v, want 000001 first", got)
+	}
+}
@@ -0,0 +1,121 @@
+package cninfo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Market is a stock universe published by cninfo. Its value is both the
+// prefix of the stock list file and the announcement query column.
+type Market string
+
+const (
+	MarketAShare Market = "szse"
+	MarketHK     Market = "hke"
+	MarketFund   Market = "fund"
+	MarketBond   Market = "bond"
+)
+
+// Markets are all known markets.
+var Markets = []Market{MarketAShare, MarketHK, MarketFund, MarketBond}
+
+var marketNames = map[Market]string{
+	MarketAShare: "a-share",
+	MarketHK:     "hk",
+	MarketFund:   "fund",
+	MarketBond:   "bond",
+}
+
+// Name returns the short name of m, e.g. "hk", or m itself if unknown.
+func (m Market) Name() string {
+	if name, ok := marketNames[m]; ok {
+		return name
+	}
+	return string(m)
+}
+
+// ParseMarket accepts either a short name returned by Market.Name or a raw
+// cninfo market value.
+func ParseMarket(s string) (Market, error) {
+	for market, name := range marketNames {
+		if s == name || s == string(market) {
+			return market, nil
+		}
+	}
+	return "", fmt.Errorf("unknown market %q", s)
+}
+
+func (m Market) column() string {
+	if m == "" {
+		return string(MarketAShare)
+	}
+	return string(m)
+}
+
+// cninfo does not file HK disclosures under the A-share categories, so
+// periodic reports of HK stocks are recognized by their titles instead.
+var hkCategoryTitles = map[Category][]string{
+	CategoryAnnualReport:       {"年报", "年度报告", "ANNUAL REPORT"},
+	CategorySemiAnnualReport:   {"中报", "中期报告", "半年报", "半年度报告", "INTERIM REPORT", "SEMI-ANNUAL REPORT", "SEMI ANNUAL REPORT", "SEMIANNUAL REPORT"},
+	CategoryFirstQuarterReport: {"第一季度", "FIRST QUARTER"},
+	CategoryThirdQuarterReport: {"第三季度", "THIRD QUARTER"},
+}
+
+// hkCategoryExcludedTitles are the titles of other categories containing
+// the titles of a category, e.g. "半年度报告" contains "年度报告" and
+// "SEMI-ANNUAL REPORT" contains "ANNUAL REPORT".
+var hkCategoryExcludedTitles = map[Category][]string{
+	CategoryAnnualReport: {"半年报", "半年度", "SEMI-ANNUAL", "SEMI ANNUAL", "SEMIANNUAL", "INTERIM"},
+}
+
+// IsHKCategoryTitle reports whether title, case insensitive, is the title
+// of a report of category of an HK stock.
+func IsHKCategoryTitle(category Category, title string) bool {
+	title = strings.ToUpper(title)
+	if containsAny(title, hkCategoryExcludedTitles[category]) {
+		return false
+	}
+	return containsAny(title, hkCategoryTitles[category])
+}
+
+func matchHKCategory(category Category, announcements []*Announcement) []*Announcement {
+	var matched []*Announcement
+	for _, announcement := range announcements {
+		if IsHKCategoryTitle(category, announcement.AnnouncementTitle) {
+			matched = append(matched, announcement)
+		}
+	}
+	return matched
+}
+
+func (s *Source) RequestMarketStockList(market Market) (*StockListResponse, error) {
+	return s.RequestMarketStockListContext(context.Background(), market)
+}
+
+func (s *Source) RequestMarketStockListContext(ctx context.Context, market Market) (*StockListResponse, error) {
+	req, err := s.newRequest(ctx, "GET", s.baseURL()+"/new/data/"+market.column()+"_stock.json", nil)
+	if err != nil {
+		return nil, err
+	}
+	p := &StockListResponse{}
+	if err := s.do(ctx, req, p); err != nil {
+		return nil, err
+	}
+	for _, stock := range p.StockList {
+		stock.Market = market
+	}
+	return p, nil
+}
+
+func (s *Source) GetMarketStockList(market Market) ([]*Stock, error) {
+	return s.GetMarketStockListContext(context.Background(), market)
+}
+
+func (s *Source) GetMarketStockListContext(ctx context.Context, market Market) ([]*Stock, error) {
+	p, err := s.RequestMarketStockListContext(ctx, market)
+	if err != nil {
+		return nil, err
+	}
+	return p.StockList, nil
+}
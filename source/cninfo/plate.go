@@ -0,0 +1,193 @@
+package cninfo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Plate is a board of the A-share market, the plate filter of the
+// announcement query.
+type Plate string
+
+const (
+	PlateSZ      Plate = "sz"
+	PlateSZMain  Plate = "szmb"
+	PlateChiNext Plate = "szcy"
+	PlateSH      Plate = "sh"
+	PlateSHMain  Plate = "shmb"
+	PlateSTAR    Plate = "shkcp"
+	PlateBSE     Plate = "bj"
+)
+
+// Plates are all known plates. PlateSZ and PlateSH are the whole Shenzhen
+// and Shanghai exchanges, the others the boards.
+var Plates = []Plate{PlateSZ, PlateSZMain, PlateChiNext, PlateSH, PlateSHMain, PlateSTAR, PlateBSE}
+
+var plateNames = map[Plate]string{
+	PlateSZ:      "sz",
+	PlateSZMain:  "sz-main",
+	PlateChiNext: "chinext",
+	PlateSH:      "sh",
+	PlateSHMain:  "sh-main",
+	PlateSTAR:    "star",
+	PlateBSE:     "bse",
+}
+
+// Name returns the short name of p, e.g. "chinext", or p itself if unknown.
+func (p Plate) Name() string {
+	if name, ok := plateNames[p]; ok {
+		return name
+	}
+	return string(p)
+}
+
+// ParsePlate accepts either a short name returned by Plate.Name or a raw
+// cninfo plate value.
+func ParsePlate(s string) (Plate, error) {
+	for plate, name := range plateNames {
+		if s == name || s == string(plate) {
+			return plate, nil
+		}
+	}
+	return "", fmt.Errorf("unknown plate %q", s)
+}
+
+// StockPlate returns the board of an A-share stock by its code, or "" if
+// the code is not one of an A-share.
+func StockPlate(code string) Plate {
+	switch {
+	case len(code) != 6:
+		return ""
+	case strings.HasPrefix(code, "30"):
+		return PlateChiNext
+	case strings.HasPrefix(code, "00"):
+		return PlateSZMain
+	case strings.HasPrefix(code, "68"):
+		return PlateSTAR
+	case strings.HasPrefix(code, "60"):
+		return PlateSHMain
+	case strings.HasPrefix(code, "4"), strings.HasPrefix(code, "8"), strings.HasPrefix(code, "92"):
+		return PlateBSE
+	}
+	return ""
+}
+
+// Contains reports whether the stock of code is on p.
+func (p Plate) Contains(code string) bool {
+	plate := StockPlate(code)
+	switch p {
+	case PlateSZ:
+		return plate == PlateSZMain || plate == PlateChiNext
+	case PlateSH:
+		return plate == PlateSHMain || plate == PlateSTAR
+	}
+	return plate != "" && plate == p
+}
+
+// Trade is an industry of the CSRC classification, the trade filter of the
+// announcement query.
+type Trade string
+
+const (
+	TradeAgriculture    Trade = "农、林、牧、渔业"
+	TradeMining         Trade = "采矿业"
+	TradeManufacturing  Trade = "制造业"
+	TradeUtilities      Trade = "电力、热力、燃气及水生产和供应业"
+	TradeConstruction   Trade = "建筑业"
+	TradeWholesale      Trade = "批发和零售业"
+	TradeTransportation Trade = "交通运输、仓储和邮政业"
+	TradeHospitality    Trade = "住宿和餐饮业"
+	TradeIT             Trade = "信息传输、软件和信息技术服务业"
+	TradeFinance        Trade = "金融业"
+	TradeRealEstate     Trade = "房地产业"
+	TradeLeasing        Trade = "租赁和商务服务业"
+	TradeResearch       Trade = "科学研究和技术服务业"
+	TradeEnvironment    Trade = "水利、环境和公共设施管理业"
+	TradeServices       Trade = "居民服务、修理和其他服务业"
+	TradeEducation      Trade = "教育"
+	TradeHealth         Trade = "卫生和社会工作"
+	TradeCulture        Trade = "文化、体育和娱乐业"
+	TradeConglomerate   Trade = "综合"
+)
+
+// Trades are all industries, in the order of their codes.
+var Trades = []Trade{
+	TradeAgriculture, TradeMining, TradeManufacturing, TradeUtilities, TradeConstruction,
+	TradeWholesale, TradeTransportation, TradeHospitality, TradeIT, TradeFinance,
+	TradeRealEstate, TradeLeasing, TradeResearch, TradeEnvironment, TradeServices,
+	TradeEducation, TradeHealth, TradeCulture, TradeConglomerate,
+}
+
+// Code returns the CSRC code of t, e.g. "C" for manufacturing, or t itself
+// if unknown.
+func (t Trade) Code() string {
+	for i, trade := range Trades {
+		if t == trade {
+			return string(rune('A' + i))
+		}
+	}
+	return string(t)
+}
+
+// ParseTrade accepts either a code returned by Trade.Code or a raw cninfo
+// trade value.
+func ParseTrade(s string) (Trade, error) {
+	for _, trade := range Trades {
+		if strings.EqualFold(s, trade.Code()) || s == string(trade) {
+			return trade, nil
+		}
+	}
+	return "", fmt.Errorf("unknown trade %q", s)
+}
+
+// ParsePlates parses plates separated by comma, e.g. "chinext,star". An
+// empty s is no plate.
+func ParsePlates(s string) ([]Plate, error) {
+	var plates []Plate
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		plate, err := ParsePlate(name)
+		if err != nil {
+			return nil, err
+		}
+		plates = append(plates, plate)
+	}
+	return plates, nil
+}
+
+// ParseTrades parses trades separated by comma, e.g. "C,J". An empty s is
+// no trade.
+func ParseTrades(s string) ([]Trade, error) {
+	var trades []Trade
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		trade, err := ParseTrade(name)
+		if err != nil {
+			return nil, err
+		}
+		trades = append(trades, trade)
+	}
+	return trades, nil
+}
+
+// joinPlates and joinTrades format filters the way the announcement query
+// takes several of them.
+func joinPlates(plates []Plate) string {
+	s := make([]string, len(plates))
+	for i, plate := range plates {
+		s[i] = string(plate)
+	}
+	return strings.Join(s, ";")
+}
+
+func joinTrades(trades []Trade) string {
+	s := make([]string, len(trades))
+	for i, trade := range trades {
+		s[i] = string(trade)
+	}
+	return strings.Join(s, ";")
+}
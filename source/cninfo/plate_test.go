@@ -0,0 +1,71 @@
+package cninfo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStockPlate(t *testing.T) {
+	tests := []struct {
+		code string
+		want Plate
+	}{
+		{"000001", PlateSZMain},
+		{"002594", PlateSZMain},
+		{"300750", PlateChiNext},
+		{"600000", PlateSHMain},
+		{"688981", PlateSTAR},
+		{"830799", PlateBSE},
+		{"920001", PlateBSE},
+		{"00700", ""},
+	}
+	for _, tt := range tests {
+		if got := StockPlate(tt.code); got != tt.want {
+			t.Errorf("StockPlate(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+	if !PlateSZ.Contains("300750") || PlateSZ.Contains("600000") || !PlateSH.Contains("688981") {
+		t.Error("got exchange plates not containing their boards")
+	}
+}
+
+func TestParseTrade(t *testing.T) {
+	for _, s := range []string{"C", "c", "制造业"} {
+		if trade, err := ParseTrade(s); err != nil || trade != TradeManufacturing {
+			t.Errorf("ParseTrade(%q) = %q, %v, want %q", s, trade, err, TradeManufacturing)
+		}
+	}
+	if TradeConglomerate.Code() != "S" {
+		t.Errorf("got code %s of %s, want S", TradeConglomerate.Code(), TradeConglomerate)
+	}
+	if _, err := ParseTrade("Z"); err == nil {
+		t.Error("got no error for unknown trade Z")
+	}
+}
+
+func TestQueryBuilder(t *testing.T) {
+	q, err := NewQuery().Category(CategoryAnnualReport).Plates(PlateChiNext, PlateSTAR).Trades(TradeIT).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := q.request()
+	if r.Plate != "szcy;shkcp" || r.Trade != string(TradeIT) || r.Column != string(MarketAShare) {
+		t.Fatalf("got request %+v", r)
+	}
+
+	now := time.Now()
+	invalid := []*QueryBuilder{
+		NewQuery().Plates("nasdaq"),
+		NewQuery().Trades("IT"),
+		NewQuery().Market(MarketHK).Plates(PlateSZ),
+		NewQuery().Stock(&Stock{Code: "00700", Market: MarketHK}).Trades(TradeIT),
+		NewQuery().Category("category_unknown"),
+		NewQuery().Between(now, now.AddDate(0, 0, -1)),
+		NewQuery().Sort(SortByTime, "up"),
+	}
+	for _, b := range invalid {
+		if q, err := b.Build(); err == nil {
+			t.Errorf("got no error for query %+v", q)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package cninfo
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	// DefaultPollInterval is the interval of a Poller without one.
+	DefaultPollInterval = time.Minute
+
+	// DefaultPollPages is the number of pages a Poller without MaxPages
+	// reads per poll to catch up.
+	DefaultPollPages = 10
+)
+
+// PollMark is the high-water mark of a Poller: the time of the latest
+// announcement seen and the IDs of the ones seen at that time, as cninfo
+// publishes many at once.
+type PollMark struct {
+	Time int64    `json:"time"`
+	IDs  []string `json:"ids"`
+}
+
+// seen reports whether announcement is at or before the mark.
+func (m *PollMark) seen(announcement *Announcement) bool {
+	if announcement.AnnouncementTime != m.Time {
+		return announcement.AnnouncementTime < m.Time
+	}
+	for _, id := range m.IDs {
+		if id == announcement.AnnouncementID {
+			return true
+		}
+	}
+	return false
+}
+
+// advance moves the mark past announcements, which are not seen yet.
+func (m *PollMark) advance(announcements []*Announcement) {
+	for _, announcement := range announcements {
+		if announcement.AnnouncementTime > m.Time {
+			m.Time, m.IDs = announcement.AnnouncementTime, nil
+		}
+		if announcement.AnnouncementTime == m.Time {
+			m.IDs = append(m.IDs, announcement.AnnouncementID)
+		}
+	}
+}
+
+// ReadPollMark reads a mark written by WritePollMark. It returns nil if
+// the file does not exist.
+func ReadPollMark(name string) (*PollMark, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	m := &PollMark{}
+	if err := json.Unmarshal(b, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// WritePollMark writes m to name, replacing it at once so that a crash
+// leaves either the old or the new mark.
+func WritePollMark(name string, m *PollMark) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
+
+// PollEvent is a new announcement, or the error of a poll. The Poller keeps
+// polling after errors.
+type PollEvent struct {
+	Announcement *Announcement
+	Err          error
+}
+
+// Poller polls the latest announcements and emits every new one once.
+type Poller struct {
+	Source *Source
+
+	// Query filters the announcements. Its dates and sort are ignored. If
+	// nil, every A-share announcement is polled.
+	Query *SearchQuery
+
+	// Interval is the time between polls. If not positive,
+	// DefaultPollInterval is used.
+	Interval time.Duration
+
+	// MaxPages bounds the pages read per poll. Announcements beyond them,
+	// published faster than polled, are skipped. If not positive,
+	// DefaultPollPages is used.
+	MaxPages int
+
+	// Mark is the high-water mark. If nil, the first poll only sets it to
+	// the latest announcements without emitting them.
+	Mark *PollMark
+
+	// File, if set, keeps Mark across restarts. It is read before the first
+	// poll when Mark is nil and written by Run. Callers of Poll write it
+	// with WritePollMark once they handled the announcements.
+	File string
+}
+
+func (p *Poller) interval() time.Duration {
+	if p.Interval <= 0 {
+		return DefaultPollInterval
+	}
+	return p.Interval
+}
+
+func (p *Poller) maxPages() int {
+	if p.MaxPages <= 0 {
+		return DefaultPollPages
+	}
+	return p.MaxPages
+}
+
+// Poll returns the announcements published since Mark, oldest first, and
+// moves Mark past them. Responses are never served from the cache of
+// Source, which would hide new announcements.
+func (p *Poller) Poll(ctx context.Context) ([]*Announcement, error) {
+	if err := p.readMark(); err != nil {
+		return nil, err
+	}
+
+	s := &Source{}
+	if p.Source != nil {
+		*s = *p.Source
+	}
+	s.Cache = nil
+	q := &SearchQuery{}
+	if p.Query != nil {
+		*q = *p.Query
+	}
+	q.End = Now().AddDate(0, 0, 1)
+	q.Start = q.End.AddDate(0, 0, -2)
+	if p.Mark != nil && p.Mark.Time != 0 {
+		if t := time.UnixMilli(p.Mark.Time); t.Before(q.Start) {
+			q.Start = t
+		}
+	}
+	q.SortName, q.SortType = SortByTime, SortDesc
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	r := q.request()
+
+	var announcements []*Announcement
+	var latest int64
+	for r.PageNum < p.maxPages() {
+		r.PageNum++
+		resp, err := s.RequestHisAnnouncementQueryContext(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+		var oldest int64
+		for i, announcement := range resp.Announcements {
+			if announcement.AnnouncementTime > latest {
+				latest = announcement.AnnouncementTime
+			}
+			if i == 0 || announcement.AnnouncementTime < oldest {
+				oldest = announcement.AnnouncementTime
+			}
+			if p.Mark == nil || !p.Mark.seen(announcement) {
+				announcements = append(announcements, announcement)
+			}
+		}
+		// The announcements of one time can be split across pages, so read
+		// on until a page reaches past the mark, or without a mark past the
+		// latest time the mark is set to.
+		mark := latest
+		if p.Mark != nil {
+			mark = p.Mark.Time
+		}
+		if len(resp.Announcements) == 0 || oldest < mark || !resp.HasMore || r.PageNum >= resp.Totalpages {
+			break
+		}
+	}
+	announcements = uniqueAnnouncements(announcements)
+	sort.SliceStable(announcements, func(i, j int) bool {
+		return announcements[i].AnnouncementTime < announcements[j].AnnouncementTime
+	})
+
+	if p.Mark == nil {
+		p.Mark = &PollMark{}
+		p.Mark.advance(announcements)
+		return nil, nil
+	}
+	if len(announcements) == 0 {
+		return nil, nil
+	}
+	p.Mark.advance(announcements)
+	return announcements, nil
+}
+
+func (p *Poller) readMark() error {
+	if p.Mark != nil || p.File == "" {
+		return nil
+	}
+	m, err := ReadPollMark(p.File)
+	if err != nil {
+		return err
+	}
+	p.Mark = m
+	return nil
+}
+
+func (p *Poller) writeMark() error {
+	if p.File == "" {
+		return nil
+	}
+	return WritePollMark(p.File, p.Mark)
+}
+
+// Run polls every Interval until ctx is done and streams the new
+// announcements, then closes the channel. Mark is written to File as the
+// announcements are received, so that after a restart the ones received
+// are not emitted again. Callers must drain the channel or cancel ctx.
+func (p *Poller) Run(ctx context.Context) <-chan *PollEvent {
+	events := make(chan *PollEvent)
+	go func() {
+		defer close(events)
+		send := func(event *PollEvent) bool {
+			select {
+			case events <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		timer := time.NewTimer(0)
+		defer timer.Stop()
+		for {
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				return
+			}
+			if err := p.readMark(); err != nil {
+				if !send(&PollEvent{Err: err}) {
+					return
+				}
+				timer.Reset(p.interval())
+				continue
+			}
+			started := p.Mark != nil
+			var mark PollMark
+			if started {
+				mark = PollMark{Time: p.Mark.Time, IDs: append([]string(nil), p.Mark.IDs...)}
+			}
+			announcements, err := p.Poll(ctx)
+			if err != nil && ctx.Err() == nil && !send(&PollEvent{Err: err}) {
+				return
+			}
+			n := 0
+			for _, announcement := range announcements {
+				if !send(&PollEvent{Announcement: announcement}) {
+					break
+				}
+				n++
+			}
+			if n < len(announcements) {
+				// Only keep the mark of the announcements received.
+				mark.advance(announcements[:n])
+				p.Mark = &mark
+			}
+			if n > 0 || !started && p.Mark != nil {
+				if err := p.writeMark(); err != nil && !send(&PollEvent{Err: err}) {
+					return
+				}
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			timer.Reset(p.interval())
+		}
+	}()
+	return events
+}
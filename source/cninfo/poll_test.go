@@ -0,0 +1,117 @@
+package cninfo_test
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/chamzzzzzz/financial/source/cninfo"
+	"github.com/chamzzzzzz/financial/source/cninfo/cninfotest"
+)
+
+func TestPoller(t *testing.T) {
+	now := cninfo.Now()
+	dataset := &cninfotest.Dataset{Announcements: make(map[cninfo.Category][]*cninfo.Announcement)}
+	publish := func(ids ...int) {
+		for _, id := range ids {
+			dataset.Announcements[cninfo.CategoryAnnualReport] = append(dataset.Announcements[cninfo.CategoryAnnualReport], &cninfo.Announcement{
+				AnnouncementID:   strconv.Itoa(id),
+				SecCode:          "000001",
+				AnnouncementTime: now.Add(time.Duration(id/2) * time.Second).UnixMilli(),
+			})
+		}
+	}
+	ids := func(announcements []*cninfo.Announcement) string {
+		var s string
+		for _, announcement := range announcements {
+			s += announcement.AnnouncementID + ","
+		}
+		return s
+	}
+	publish(1, 2, 3)
+	server := cninfotest.NewServer(dataset)
+	defer server.Close()
+	file := filepath.Join(t.TempDir(), "poll.json")
+	ctx := context.Background()
+
+	p := &cninfo.Poller{Source: server.Source(), File: file}
+	if announcements, err := p.Poll(ctx); err != nil || len(announcements) != 0 {
+		t.Fatalf("got %s, %v on first poll, want nothing", ids(announcements), err)
+	}
+	publish(4, 5)
+	if announcements, err := p.Poll(ctx); err != nil || ids(announcements) != "4,5," {
+		t.Fatalf("got %s, %v, want 4,5,", ids(announcements), err)
+	}
+	if announcements, err := p.Poll(ctx); err != nil || len(announcements) != 0 {
+		t.Fatalf("got %s, %v on poll without news, want nothing", ids(announcements), err)
+	}
+
+	if err := cninfo.WritePollMark(file, p.Mark); err != nil {
+		t.Fatal(err)
+	}
+
+	// A restarted poller goes on from the mark written, which only covers
+	// the announcements received.
+	run := func(n int) string {
+		p := &cninfo.Poller{Source: server.Source(), File: file, Interval: time.Millisecond}
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		var got []*cninfo.Announcement
+		for event := range p.Run(ctx) {
+			if event.Err != nil {
+				t.Fatal(event.Err)
+			}
+			if got = append(got, event.Announcement); len(got) == n {
+				cancel()
+			}
+		}
+		return ids(got)
+	}
+	publish(6, 7)
+	if got := run(1); got != "6," {
+		t.Fatalf("got %s, want 6,", got)
+	}
+	publish(8)
+	if got := run(2); got != "7,8," {
+		t.Fatalf("got %s after restart, want 7,8,", got)
+	}
+}
+
+func TestPollerSameTime(t *testing.T) {
+	at := cninfo.Now().Add(-time.Hour).UnixMilli()
+	dataset := &cninfotest.Dataset{Announcements: make(map[cninfo.Category][]*cninfo.Announcement)}
+	publish := func(from, to int) {
+		for id := from; id <= to; id++ {
+			dataset.Announcements[cninfo.CategoryAnnualReport] = append(dataset.Announcements[cninfo.CategoryAnnualReport], &cninfo.Announcement{
+				AnnouncementID:   strconv.Itoa(id),
+				SecCode:          "000001",
+				AnnouncementTime: at,
+			})
+		}
+	}
+	// More announcements of one time than fit on a page.
+	publish(1, 35)
+	server := cninfotest.NewServer(dataset)
+	defer server.Close()
+	ctx := context.Background()
+
+	p := &cninfo.Poller{Source: server.Source()}
+	if announcements, err := p.Poll(ctx); err != nil || len(announcements) != 0 {
+		t.Fatalf("got %d announcements, %v on first poll, want nothing", len(announcements), err)
+	}
+	if p.Mark.Time != at || len(p.Mark.IDs) != 35 {
+		t.Fatalf("got mark at %d of %d announcements, want all 35 at %d", p.Mark.Time, len(p.Mark.IDs), at)
+	}
+
+	// Late ones of the same time sort after the page of seen ones.
+	publish(36, 37)
+	announcements, err := p.Poll(ctx)
+	if err != nil || len(announcements) != 2 || announcements[0].AnnouncementID != "36" || announcements[1].AnnouncementID != "37" {
+		t.Fatalf("got %d announcements, %v, want 36 and 37", len(announcements), err)
+	}
+	if announcements, err := p.Poll(ctx); err != nil || len(announcements) != 0 {
+		t.Fatalf("got %d announcements, %v on poll without news, want nothing", len(announcements), err)
+	}
+}
@@ -0,0 +1,202 @@
+// Package replay records HTTP exchanges with cninfo into fixture files and
+// replays them offline, so code built on cninfo.Source can be tested
+// deterministically.
+//
+//	t, err := replay.Open("testdata/stock.json", replay.ModeFromEnv())
+//	source := &cninfo.Source{Client: &http.Client{Transport: t}}
+//	...
+//	err = t.Close()
+//
+// Run the tests with CNINFO_RECORD=1 to refresh the fixtures from the live
+// site.
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+type Mode int
+
+const (
+	ModeReplay Mode = iota
+	ModeRecord
+)
+
+// ModeFromEnv returns ModeRecord if CNINFO_RECORD is set, ModeReplay
+// otherwise.
+func ModeFromEnv() Mode {
+	if os.Getenv("CNINFO_RECORD") != "" {
+		return ModeRecord
+	}
+	return ModeReplay
+}
+
+type Request struct {
+	Method string
+	URL    string
+	Body   string `json:",omitempty"`
+}
+
+type Response struct {
+	StatusCode int
+	Header     http.Header `json:",omitempty"`
+	Body       string
+}
+
+type Interaction struct {
+	Request  Request
+	Response Response
+}
+
+// Transport is an http.RoundTripper recording to or replaying from a fixture
+// file.
+type Transport struct {
+	// Transport sends the requests while recording. If nil,
+	// http.DefaultTransport is used.
+	Transport http.RoundTripper
+
+	file         string
+	mode         Mode
+	mu           sync.Mutex
+	interactions []*Interaction
+	used         map[*Interaction]bool
+}
+
+// Open returns a transport for the fixture file. In ModeReplay the file must
+// exist.
+func Open(file string, mode Mode) (*Transport, error) {
+	t := &Transport{file: file, mode: mode, used: make(map[*Interaction]bool)}
+	if mode == ModeRecord {
+		return t, nil
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &t.interactions); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return t, nil
+}
+
+// Close writes the recorded interactions to the fixture file. It does
+// nothing in ModeReplay.
+func (t *Transport) Close() error {
+	if t.mode != ModeRecord {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(t.interactions); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(t.file), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(t.file, buf.Bytes(), 0644)
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := Request{Method: req.Method, URL: req.URL.String()}
+	if req.Body != nil && req.Body != http.NoBody {
+		// A RoundTripper must not modify req, the body is read from a copy
+		// if there is one, else it is sent with a clone of req.
+		body := req.Body
+		if req.GetBody != nil {
+			var err error
+			if body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+		b, err := io.ReadAll(body)
+		body.Close()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = string(b)
+		if req.GetBody == nil {
+			req = req.Clone(req.Context())
+			req.Body = io.NopCloser(bytes.NewReader(b))
+		}
+	}
+	if t.mode == ModeRecord {
+		return t.record(req, r)
+	}
+	return t.replay(req, r)
+}
+
+func (t *Transport) record(req *http.Request, r Request) (*http.Response, error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	b, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(b))
+	header := resp.Header.Clone()
+	header.Del("Date")
+	header.Del("Set-Cookie")
+	t.mu.Lock()
+	t.interactions = append(t.interactions, &Interaction{
+		Request:  r,
+		Response: Response{StatusCode: resp.StatusCode, Header: header, Body: string(b)},
+	})
+	t.mu.Unlock()
+	return resp, nil
+}
+
+// replay answers with the first unused interaction matching the request, or
+// the last matching one if all were used.
+func (t *Transport) replay(req *http.Request, r Request) (*http.Response, error) {
+	t.mu.Lock()
+	var matched *Interaction
+	for _, interaction := range t.interactions {
+		if interaction.Request != r {
+			continue
+		}
+		matched = interaction
+		if !t.used[interaction] {
+			break
+		}
+	}
+	if matched != nil {
+		t.used[matched] = true
+	}
+	t.mu.Unlock()
+	if matched == nil {
+		return nil, fmt.Errorf("replay: no interaction in %s for %s %s %s", t.file, r.Method, r.URL, r.Body)
+	}
+	header := matched.Response.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", matched.Response.StatusCode, http.StatusText(matched.Response.StatusCode)),
+		StatusCode:    matched.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader([]byte(matched.Response.Body))),
+		ContentLength: int64(len(matched.Response.Body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,83 @@
+package replay
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		io.WriteString(w, r.Method+" "+r.URL.Path+" "+string(b))
+	}))
+	file := filepath.Join(t.TempDir(), "fixture.json")
+
+	post := func(client *http.Client, body string) string {
+		t.Helper()
+		resp, err := client.Post(server.URL+"/query", "text/plain", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	recorder, err := Open(file, ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	post(&http.Client{Transport: recorder}, "a")
+	post(&http.Client{Transport: recorder}, "b")
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	replayer, err := Open(file, ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: replayer}
+	if got := post(client, "b"); got != "POST /query b" {
+		t.Errorf("got %q", got)
+	}
+	if got := post(client, "a"); got != "POST /query a" {
+		t.Errorf("got %q", got)
+	}
+	if _, err := client.Post(server.URL+"/query", "text/plain", strings.NewReader("c")); err == nil {
+		t.Error("got no error for unrecorded request")
+	}
+}
+
+func TestRoundTripKeepsRequest(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "fixture.json")
+	recorder, err := Open(file, ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder.Close()
+	replayer, err := Open(file, ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest("POST", "http://example.com/query", strings.NewReader("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := req.Body
+	replayer.RoundTrip(req)
+	if req.Body != body {
+		t.Fatal("request body replaced")
+	}
+	if b, _ := io.ReadAll(req.Body); string(b) != "a" {
+		t.Errorf("got request body %q", b)
+	}
+}
@@ -0,0 +1,40 @@
+// Package replaytest provides a cninfo.Source replaying fixture files for
+// tests, apart from package replay so that programs importing replay do not
+// import testing.
+//
+//	source := replaytest.NewSource(t, filepath.Join("testdata", "stock_list.json"))
+package replaytest
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/chamzzzzzz/financial/source/cninfo"
+	"github.com/chamzzzzzz/financial/source/cninfo/replay"
+)
+
+// NewSource returns a source replaying the fixture file, or recording it
+// with CNINFO_RECORD set, without rate limit and retries. The fixture is
+// written when the test ends.
+func NewSource(t testing.TB, file string) *cninfo.Source {
+	t.Helper()
+	file, err := filepath.Abs(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport, err := replay.Open(file, replay.ModeFromEnv())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := transport.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	return &cninfo.Source{
+		Client:  &http.Client{Transport: transport},
+		Limiter: cninfo.NewRateLimiter(0, 1),
+		Retry:   cninfo.NoRetry,
+	}
+}
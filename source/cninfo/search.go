@@ -0,0 +1,261 @@
+package cninfo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	SortByTime = "time"
+	SortByCode = "code"
+
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
+// SearchQuery describes a full-text announcement search. Zero fields are
+// not filtered on, except the date range which defaults to the last year.
+type SearchQuery struct {
+	Market   Market
+	Stock    *Stock
+	Keyword  string
+	Category Category
+	Plates   []Plate
+	Trades   []Trade
+	Start    time.Time
+	End      time.Time
+	SortName string
+	SortType string
+}
+
+func (q *SearchQuery) request() *HisAnnouncementQueryRequest {
+	r := &HisAnnouncementQueryRequest{
+		Searchkey: q.Keyword,
+		Category:  string(q.Category),
+		Plate:     joinPlates(q.Plates),
+		Trade:     joinTrades(q.Trades),
+		SortName:  q.SortName,
+		SortType:  q.SortType,
+		PageSize:  queryPageSize,
+		IsHLtitle: "false",
+	}
+	if q.Market != "" {
+		r.Column = q.Market.column()
+	}
+	if q.Stock != nil {
+		if q.Stock.Market != "" {
+			r.Column = q.Stock.Market.column()
+		}
+		r.Stock = strings.Join([]string{q.Stock.Code, q.Stock.OrgID}, ",")
+	}
+	if !q.Start.IsZero() || !q.End.IsZero() {
+		start, end := q.Start, q.End
+		if end.IsZero() {
+			end = Now().AddDate(0, 0, 1)
+		}
+		if start.IsZero() {
+			start = end.AddDate(-1, 0, 0)
+		}
+		r.SeDate = fmt.Sprintf("%s~%s", formatDate(start), formatDate(end))
+	}
+	return r
+}
+
+// Validate checks that the filters of q are known to cninfo and fit
+// together, e.g. that plates and trades are only used for A-shares.
+func (q *SearchQuery) Validate() error {
+	market := q.Market
+	if q.Stock != nil && q.Stock.Market != "" {
+		market = q.Stock.Market
+	}
+	if _, ok := marketNames[market]; market != "" && !ok {
+		return fmt.Errorf("unknown market %q", market)
+	}
+	if _, ok := categoryNames[q.Category]; q.Category != "" && !ok {
+		return fmt.Errorf("unknown category %q", q.Category)
+	}
+	for _, plate := range q.Plates {
+		if _, ok := plateNames[plate]; !ok {
+			return fmt.Errorf("unknown plate %q", plate)
+		}
+	}
+	for _, trade := range q.Trades {
+		if _, err := ParseTrade(string(trade)); err != nil {
+			return err
+		}
+	}
+	if (len(q.Plates) > 0 || len(q.Trades) > 0) && market.column() != string(MarketAShare) {
+		return fmt.Errorf("plates and trades are only of the %s market", MarketAShare.Name())
+	}
+	if !q.Start.IsZero() && !q.End.IsZero() && q.Start.After(q.End) {
+		return errors.New("start time must be before end time")
+	}
+	if q.SortName != "" && q.SortName != SortByTime && q.SortName != SortByCode {
+		return fmt.Errorf("unknown sort name %q", q.SortName)
+	}
+	if q.SortType != "" && q.SortType != SortAsc && q.SortType != SortDesc {
+		return fmt.Errorf("unknown sort type %q", q.SortType)
+	}
+	return nil
+}
+
+// QueryBuilder builds a validated SearchQuery.
+//
+//	q, err := cninfo.NewQuery().
+//		Category(cninfo.CategoryAnnualReport).
+//		Plates(cninfo.PlateChiNext, cninfo.PlateSTAR).
+//		Build()
+type QueryBuilder struct {
+	q SearchQuery
+}
+
+// NewQuery returns a builder of a query of every A-share announcement of
+// the last year.
+func NewQuery() *QueryBuilder {
+	return &QueryBuilder{q: SearchQuery{Market: MarketAShare}}
+}
+
+func (b *QueryBuilder) Market(market Market) *QueryBuilder {
+	b.q.Market = market
+	return b
+}
+
+func (b *QueryBuilder) Stock(stock *Stock) *QueryBuilder {
+	b.q.Stock = stock
+	return b
+}
+
+func (b *QueryBuilder) Keyword(keyword string) *QueryBuilder {
+	b.q.Keyword = keyword
+	return b
+}
+
+func (b *QueryBuilder) Category(category Category) *QueryBuilder {
+	b.q.Category = category
+	return b
+}
+
+// Plates adds plates to the query, announcements of any of them match.
+func (b *QueryBuilder) Plates(plates ...Plate) *QueryBuilder {
+	b.q.Plates = append(b.q.Plates, plates...)
+	return b
+}
+
+// Trades adds trades to the query, announcements of any of them match.
+func (b *QueryBuilder) Trades(trades ...Trade) *QueryBuilder {
+	b.q.Trades = append(b.q.Trades, trades...)
+	return b
+}
+
+func (b *QueryBuilder) Between(start, end time.Time) *QueryBuilder {
+	b.q.Start, b.q.End = start, end
+	return b
+}
+
+func (b *QueryBuilder) Sort(name, order string) *QueryBuilder {
+	b.q.SortName, b.q.SortType = name, order
+	return b
+}
+
+// Build returns the query, or the first problem found by Validate.
+func (b *QueryBuilder) Build() (*SearchQuery, error) {
+	q := b.q
+	q.Plates = append([]Plate(nil), b.q.Plates...)
+	q.Trades = append([]Trade(nil), b.q.Trades...)
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	return &q, nil
+}
+
+// AnnouncementIterator walks the announcements of a search page by page.
+//
+//	it := source.Search(ctx, q)
+//	for it.Next() {
+//		announcement := it.Announcement()
+//	}
+//	if err := it.Err(); err != nil {
+//		// handle err
+//	}
+type AnnouncementIterator struct {
+	ctx     context.Context
+	source  *Source
+	request *HisAnnouncementQueryRequest
+	page    []*Announcement
+	current *Announcement
+	seen    map[string]struct{}
+	done    bool
+	err     error
+	total   int
+}
+
+// Search returns an iterator over every announcement matching q. Requests
+// are only sent as the iterator advances. An invalid q is reported by Err.
+func (s *Source) Search(ctx context.Context, q *SearchQuery) *AnnouncementIterator {
+	return &AnnouncementIterator{
+		ctx:     ctx,
+		source:  s,
+		request: q.request(),
+		seen:    make(map[string]struct{}),
+		err:     q.Validate(),
+	}
+}
+
+// Next advances to the next announcement and reports whether there is one.
+// A result larger than cninfo lets a query page through stops with an
+// error once the reachable announcements are walked.
+func (it *AnnouncementIterator) Next() bool {
+	for {
+		for len(it.page) > 0 {
+			it.current, it.page = it.page[0], it.page[1:]
+			if _, ok := it.seen[it.current.AnnouncementID]; ok {
+				continue
+			}
+			it.seen[it.current.AnnouncementID] = struct{}{}
+			return true
+		}
+		if it.done || it.err != nil {
+			it.current = nil
+			return false
+		}
+		it.fetch()
+	}
+}
+
+func (it *AnnouncementIterator) fetch() {
+	it.request.PageNum++
+	p, err := it.source.RequestHisAnnouncementQueryContext(it.ctx, it.request)
+	if err != nil {
+		it.err = err
+		return
+	}
+	it.total = p.TotalAnnouncement
+	it.page = p.Announcements
+	if !p.HasMore || it.request.PageNum >= p.Totalpages || it.request.PageNum >= queryMaxPages || len(p.Announcements) == 0 {
+		it.done = true
+	}
+	// cninfo serves no more than queryMaxPages pages, the rest of a larger
+	// result can only be had by narrowing q. The last page is still walked.
+	if it.done && it.request.PageNum*it.request.PageSize < p.TotalAnnouncement {
+		it.err = fmt.Errorf("%w, only %d of %d can be paged through, narrow the query", errIncomplete, it.request.PageNum*it.request.PageSize, p.TotalAnnouncement)
+	}
+}
+
+// Announcement returns the current announcement.
+func (it *AnnouncementIterator) Announcement() *Announcement {
+	return it.current
+}
+
+// Total returns the number of matching announcements reported by cninfo,
+// known after the first call to Next.
+func (it *AnnouncementIterator) Total() int {
+	return it.total
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *AnnouncementIterator) Err() error {
+	return it.err
+}
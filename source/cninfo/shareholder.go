@@ -0,0 +1,174 @@
+package cninfo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Shareholder is one entry of a top-ten shareholders list.
+type Shareholder struct {
+	EndDate string  `json:"F001D"`
+	Name    string  `json:"F002V"`
+	Shares  float64 `json:"F003N"`
+	Ratio   float64 `json:"F004N"`
+	Nature  string  `json:"F005V"`
+}
+
+// ShareholderCount is the number of shareholders at the end of a reporting
+// period.
+type ShareholderCount struct {
+	EndDate   string  `json:"F001D"`
+	Count     float64 `json:"F002N"`
+	AvgShares float64 `json:"F003N"`
+}
+
+type ShareholderResponse struct {
+	Path string `json:"path"`
+	Code any    `json:"code"`
+	Msg  string `json:"msg"`
+	Data struct {
+		Total      int            `json:"total"`
+		Count      int            `json:"count"`
+		ResultMsg  string         `json:"resultMsg"`
+		ResultCode string         `json:"resultCode"`
+		Records    []*Shareholder `json:"records"`
+	} `json:"data"`
+}
+
+func (r *ShareholderResponse) GetCodeString() string {
+	return fmt.Sprintf("%v", r.Code)
+}
+
+type ShareholderCountResponse struct {
+	Path string `json:"path"`
+	Code any    `json:"code"`
+	Msg  string `json:"msg"`
+	Data struct {
+		Total      int                 `json:"total"`
+		Count      int                 `json:"count"`
+		ResultMsg  string              `json:"resultMsg"`
+		ResultCode string              `json:"resultCode"`
+		Records    []*ShareholderCount `json:"records"`
+	} `json:"data"`
+}
+
+func (r *ShareholderCountResponse) GetCodeString() string {
+	return fmt.Sprintf("%v", r.Code)
+}
+
+// ShareholderPeriod is a top-ten shareholders list of one reporting period.
+type ShareholderPeriod struct {
+	EndDate      string
+	Shareholders []*Shareholder
+}
+
+// Ratio returns the total holding ratio of the shareholders in percent.
+func (p *ShareholderPeriod) Ratio() float64 {
+	var ratio float64
+	for _, shareholder := range p.Shareholders {
+		ratio += shareholder.Ratio
+	}
+	return ratio
+}
+
+// GroupShareholders groups shareholders by reporting period, latest first.
+func GroupShareholders(shareholders []*Shareholder) []*ShareholderPeriod {
+	m := make(map[string]*ShareholderPeriod)
+	var periods []*ShareholderPeriod
+	for _, shareholder := range shareholders {
+		p := m[shareholder.EndDate]
+		if p == nil {
+			p = &ShareholderPeriod{EndDate: shareholder.EndDate}
+			m[shareholder.EndDate] = p
+			periods = append(periods, p)
+		}
+		p.Shareholders = append(p.Shareholders, shareholder)
+	}
+	sort.Slice(periods, func(i, j int) bool { return periods[i].EndDate > periods[j].EndDate })
+	return periods
+}
+
+func (s *Source) RequestTopTenShareholders(stockCode string) (*ShareholderResponse, error) {
+	return s.RequestTopTenShareholdersContext(context.Background(), stockCode)
+}
+
+func (s *Source) RequestTopTenShareholdersContext(ctx context.Context, stockCode string) (*ShareholderResponse, error) {
+	p := &ShareholderResponse{}
+	if err := s.getData20(ctx, "/shareholders/getTopTenStockholders", stockCode, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *Source) RequestTopTenTradableShareholders(stockCode string) (*ShareholderResponse, error) {
+	return s.RequestTopTenTradableShareholdersContext(context.Background(), stockCode)
+}
+
+func (s *Source) RequestTopTenTradableShareholdersContext(ctx context.Context, stockCode string) (*ShareholderResponse, error) {
+	p := &ShareholderResponse{}
+	if err := s.getData20(ctx, "/shareholders/getTopTenCirculatingStockholders", stockCode, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *Source) RequestShareholderCount(stockCode string) (*ShareholderCountResponse, error) {
+	return s.RequestShareholderCountContext(context.Background(), stockCode)
+}
+
+func (s *Source) RequestShareholderCountContext(ctx context.Context, stockCode string) (*ShareholderCountResponse, error) {
+	p := &ShareholderCountResponse{}
+	if err := s.getData20(ctx, "/shareholders/getStockholderNumber", stockCode, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *Source) GetTopTenShareholders(stock *Stock) ([]*ShareholderPeriod, error) {
+	return s.GetTopTenShareholdersContext(context.Background(), stock)
+}
+
+func (s *Source) GetTopTenShareholdersContext(ctx context.Context, stock *Stock) ([]*ShareholderPeriod, error) {
+	p, err := s.RequestTopTenShareholdersContext(ctx, stock.Code)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCode(p.GetCodeString(), p.Msg); err != nil {
+		return nil, err
+	}
+	return GroupShareholders(p.Data.Records), nil
+}
+
+func (s *Source) GetTopTenTradableShareholders(stock *Stock) ([]*ShareholderPeriod, error) {
+	return s.GetTopTenTradableShareholdersContext(context.Background(), stock)
+}
+
+func (s *Source) GetTopTenTradableShareholdersContext(ctx context.Context, stock *Stock) ([]*ShareholderPeriod, error) {
+	p, err := s.RequestTopTenTradableShareholdersContext(ctx, stock.Code)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCode(p.GetCodeString(), p.Msg); err != nil {
+		return nil, err
+	}
+	return GroupShareholders(p.Data.Records), nil
+}
+
+// GetShareholderCounts returns the shareholder count history, latest first.
+func (s *Source) GetShareholderCounts(stock *Stock) ([]*ShareholderCount, error) {
+	return s.GetShareholderCountsContext(context.Background(), stock)
+}
+
+func (s *Source) GetShareholderCountsContext(ctx context.Context, stock *Stock) ([]*ShareholderCount, error) {
+	p, err := s.RequestShareholderCountContext(ctx, stock.Code)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCode(p.GetCodeString(), p.Msg); err != nil {
+		return nil, err
+	}
+	counts := p.Data.Records
+	sort.SliceStable(counts, func(i, j int) bool { return counts[i].EndDate > counts[j].EndDate })
+	return counts, nil
+}
@@ -0,0 +1,41 @@
+package cninfo_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/chamzzzzzz/financial/source/cninfo"
+	"github.com/chamzzzzzz/financial/source/cninfo/replay/replaytest"
+)
+
+func TestGetShareholders(t *testing.T) {
+	source := replaytest.NewSource(t, filepath.Join("testdata", "shareholder.json"))
+	stock := &cninfo.Stock{Code: "000001"}
+
+	periods, err := source.GetTopTenShareholders(stock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(periods) != 2 || periods[0].EndDate != "2023-03-31" || len(periods[0].Shareholders) != 2 {
+		t.Fatalf("got periods %+v, want 2023-03-31 and 2022-12-31 of 2 shareholders", periods)
+	}
+	if s := periods[1].Shareholders[1]; s.Name != "香港中央结算有限公司" || s.Shares != 1406542193 || s.Ratio != 7.25 || s.Nature != "境外法人" {
+		t.Fatalf("got shareholder %+v", s)
+	}
+	if ratio := periods[0].Ratio(); ratio < 56.29 || ratio > 56.30 {
+		t.Fatalf("got ratio %g, want 56.29", ratio)
+	}
+
+	tradable, err := source.GetTopTenTradableShareholders(stock)
+	if err != nil || len(tradable) != 2 || tradable[0].Ratio() != 49.56 {
+		t.Fatalf("got tradable %+v, %v", tradable, err)
+	}
+
+	counts, err := source.GetShareholderCounts(stock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counts) != 2 || counts[0].EndDate != "2023-03-31" || counts[0].Count != 525000 || counts[0].AvgShares != 36964 {
+		t.Fatalf("got counts %+v, want 2023-03-31 first", counts)
+	}
+}
@@ -0,0 +1,307 @@
+package cninfo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The data20 financial endpoints return one record per item, e.g.
+// {"index": "营业总收入", "2022-12-31": 1.0e10, "2021-12-31": ...}. Records are
+// pivoted into one statement per reporting period; every item stays
+// available by its Chinese name in Items and the common ones are copied to
+// typed fields.
+
+// StatementItems are the items of one reporting period by name.
+type StatementItems map[string]float64
+
+// Value returns the first of names present in the items.
+func (items StatementItems) Value(names ...string) float64 {
+	for _, name := range names {
+		if v, ok := items[name]; ok {
+			return v
+		}
+	}
+	return 0
+}
+
+type MainIndicators struct {
+	Period      string
+	EPS         float64
+	BVPS        float64
+	ROE         float64
+	GrossMargin float64
+	NetMargin   float64
+	DebtRatio   float64
+	Items       StatementItems
+}
+
+type BalanceSheet struct {
+	Period             string
+	Cash               float64
+	CurrentAssets      float64
+	TotalAssets        float64
+	CurrentLiabilities float64
+	TotalLiabilities   float64
+	TotalEquity        float64
+	Items              StatementItems
+}
+
+type IncomeStatement struct {
+	Period                string
+	Revenue               float64
+	OperatingProfit       float64
+	TotalProfit           float64
+	NetProfit             float64
+	NetProfitAttributable float64
+	EPS                   float64
+	Items                 StatementItems
+}
+
+type CashFlowStatement struct {
+	Period            string
+	OperatingCashFlow float64
+	InvestingCashFlow float64
+	FinancingCashFlow float64
+	NetCashFlow       float64
+	Items             StatementItems
+}
+
+// FinancialStatements are all statements of a stock keyed by reporting
+// period, e.g. "2022-12-31".
+type FinancialStatements struct {
+	MainIndicators     map[string]*MainIndicators
+	BalanceSheets      map[string]*BalanceSheet
+	IncomeStatements   map[string]*IncomeStatement
+	CashFlowStatements map[string]*CashFlowStatement
+}
+
+// Periods returns every reporting period of any statement, latest first.
+func (f *FinancialStatements) Periods() []string {
+	m := make(map[string]struct{})
+	for period := range f.MainIndicators {
+		m[period] = struct{}{}
+	}
+	for period := range f.BalanceSheets {
+		m[period] = struct{}{}
+	}
+	for period := range f.IncomeStatements {
+		m[period] = struct{}{}
+	}
+	for period := range f.CashFlowStatements {
+		m[period] = struct{}{}
+	}
+	var periods []string
+	for period := range m {
+		periods = append(periods, period)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(periods)))
+	return periods
+}
+
+type StatementResponse struct {
+	Path string `json:"path"`
+	Code any    `json:"code"`
+	Msg  string `json:"msg"`
+	Data struct {
+		Total      int                          `json:"total"`
+		Count      int                          `json:"count"`
+		ResultMsg  string                       `json:"resultMsg"`
+		ResultCode string                       `json:"resultCode"`
+		Records    []map[string]json.RawMessage `json:"records"`
+	} `json:"data"`
+}
+
+func (r *StatementResponse) GetCodeString() string {
+	return fmt.Sprintf("%v", r.Code)
+}
+
+// StatementPeriod is the items of one reporting period.
+type StatementPeriod struct {
+	Period string
+	Items  StatementItems
+}
+
+// Periods pivots the records into items by reporting period, latest first.
+// Only keys shaped like dates, e.g. "2022-12-31", are taken for periods.
+func (r *StatementResponse) Periods() []*StatementPeriod {
+	m := make(map[string]*StatementPeriod)
+	var periods []*StatementPeriod
+	for _, record := range r.Data.Records {
+		var name string
+		if err := json.Unmarshal(record["index"], &name); err != nil || name == "" {
+			continue
+		}
+		for key, raw := range record {
+			if _, err := time.Parse("2006-01-02", key); err != nil {
+				continue
+			}
+			v, ok := statementValue(raw)
+			if !ok {
+				continue
+			}
+			p := m[key]
+			if p == nil {
+				p = &StatementPeriod{Period: key, Items: make(StatementItems)}
+				m[key] = p
+				periods = append(periods, p)
+			}
+			p.Items[name] = v
+		}
+	}
+	sort.Slice(periods, func(i, j int) bool { return periods[i].Period > periods[j].Period })
+	return periods
+}
+
+func statementValue(raw json.RawMessage) (float64, bool) {
+	var v float64
+	if err := json.Unmarshal(raw, &v); err == nil {
+		return v, true
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return 0, false
+	}
+	s = strings.TrimSuffix(strings.ReplaceAll(s, ",", ""), "%")
+	v, err := strconv.ParseFloat(s, 64)
+	return v, err == nil
+}
+
+func (s *Source) requestStatement(ctx context.Context, path, stockCode string) ([]*StatementPeriod, error) {
+	p := &StatementResponse{}
+	if err := s.getData20(ctx, path, stockCode, p); err != nil {
+		return nil, err
+	}
+	if err := checkCode(p.GetCodeString(), p.Msg); err != nil {
+		return nil, err
+	}
+	return p.Periods(), nil
+}
+
+func (s *Source) GetMainIndicators(stock *Stock) (map[string]*MainIndicators, error) {
+	return s.GetMainIndicatorsContext(context.Background(), stock)
+}
+
+func (s *Source) GetMainIndicatorsContext(ctx context.Context, stock *Stock) (map[string]*MainIndicators, error) {
+	periods, err := s.requestStatement(ctx, "/financialData/getMainIndicators", stock.Code)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]*MainIndicators, len(periods))
+	for _, p := range periods {
+		period, items := p.Period, p.Items
+		m[period] = &MainIndicators{
+			Period:      period,
+			EPS:         items.Value("基本每股收益"),
+			BVPS:        items.Value("每股净资产"),
+			ROE:         items.Value("净资产收益率", "加权净资产收益率"),
+			GrossMargin: items.Value("销售毛利率"),
+			NetMargin:   items.Value("销售净利率"),
+			DebtRatio:   items.Value("资产负债率"),
+			Items:       items,
+		}
+	}
+	return m, nil
+}
+
+func (s *Source) GetBalanceSheets(stock *Stock) (map[string]*BalanceSheet, error) {
+	return s.GetBalanceSheetsContext(context.Background(), stock)
+}
+
+func (s *Source) GetBalanceSheetsContext(ctx context.Context, stock *Stock) (map[string]*BalanceSheet, error) {
+	periods, err := s.requestStatement(ctx, "/financialData/getBalanceSheets", stock.Code)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]*BalanceSheet, len(periods))
+	for _, p := range periods {
+		period, items := p.Period, p.Items
+		m[period] = &BalanceSheet{
+			Period:             period,
+			Cash:               items.Value("货币资金"),
+			CurrentAssets:      items.Value("流动资产合计"),
+			TotalAssets:        items.Value("资产总计"),
+			CurrentLiabilities: items.Value("流动负债合计"),
+			TotalLiabilities:   items.Value("负债合计"),
+			TotalEquity:        items.Value("所有者权益合计", "股东权益合计", "所有者权益(或股东权益)合计"),
+			Items:              items,
+		}
+	}
+	return m, nil
+}
+
+func (s *Source) GetIncomeStatements(stock *Stock) (map[string]*IncomeStatement, error) {
+	return s.GetIncomeStatementsContext(context.Background(), stock)
+}
+
+func (s *Source) GetIncomeStatementsContext(ctx context.Context, stock *Stock) (map[string]*IncomeStatement, error) {
+	periods, err := s.requestStatement(ctx, "/financialData/getIncomeStatement", stock.Code)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]*IncomeStatement, len(periods))
+	for _, p := range periods {
+		period, items := p.Period, p.Items
+		m[period] = &IncomeStatement{
+			Period:                period,
+			Revenue:               items.Value("营业总收入", "营业收入"),
+			OperatingProfit:       items.Value("营业利润"),
+			TotalProfit:           items.Value("利润总额"),
+			NetProfit:             items.Value("净利润"),
+			NetProfitAttributable: items.Value("归属于母公司所有者的净利润", "归属于母公司股东的净利润"),
+			EPS:                   items.Value("基本每股收益"),
+			Items:                 items,
+		}
+	}
+	return m, nil
+}
+
+func (s *Source) GetCashFlowStatements(stock *Stock) (map[string]*CashFlowStatement, error) {
+	return s.GetCashFlowStatementsContext(context.Background(), stock)
+}
+
+func (s *Source) GetCashFlowStatementsContext(ctx context.Context, stock *Stock) (map[string]*CashFlowStatement, error) {
+	periods, err := s.requestStatement(ctx, "/financialData/getCashFlowStatement", stock.Code)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]*CashFlowStatement, len(periods))
+	for _, p := range periods {
+		period, items := p.Period, p.Items
+		m[period] = &CashFlowStatement{
+			Period:            period,
+			OperatingCashFlow: items.Value("经营活动产生的现金流量净额"),
+			InvestingCashFlow: items.Value("投资活动产生的现金流量净额"),
+			FinancingCashFlow: items.Value("筹资活动产生的现金流量净额"),
+			NetCashFlow:       items.Value("现金及现金等价物净增加额"),
+			Items:             items,
+		}
+	}
+	return m, nil
+}
+
+func (s *Source) GetFinancialStatements(stock *Stock) (*FinancialStatements, error) {
+	return s.GetFinancialStatementsContext(context.Background(), stock)
+}
+
+func (s *Source) GetFinancialStatementsContext(ctx context.Context, stock *Stock) (*FinancialStatements, error) {
+	var err error
+	f := &FinancialStatements{}
+	if f.MainIndicators, err = s.GetMainIndicatorsContext(ctx, stock); err != nil {
+		return nil, err
+	}
+	if f.BalanceSheets, err = s.GetBalanceSheetsContext(ctx, stock); err != nil {
+		return nil, err
+	}
+	if f.IncomeStatements, err = s.GetIncomeStatementsContext(ctx, stock); err != nil {
+		return nil, err
+	}
+	if f.CashFlowStatements, err = s.GetCashFlowStatementsContext(ctx, stock); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
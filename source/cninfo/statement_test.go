@@ -0,0 +1,55 @@
+package cninfo_test
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/chamzzzzzz/financial/source/cninfo"
+	"github.com/chamzzzzzz/financial/source/cninfo/replay/replaytest"
+)
+
+func TestStatementResponsePeriods(t *testing.T) {
+	r := &cninfo.StatementResponse{}
+	r.Data.Records = []map[string]json.RawMessage{
+		{"index": json.RawMessage(`"营业收入"`), "2021-12-31": json.RawMessage(`1`), "2022-12-31": json.RawMessage(`"2,000"`), "sort": json.RawMessage(`3`), "rowNum": json.RawMessage(`"4"`)},
+		{"index": json.RawMessage(`"净利润"`), "2022-12-31": json.RawMessage(`"--"`), "2020-12-31": json.RawMessage(`"12.5%"`)},
+	}
+	periods := r.Periods()
+	if len(periods) != 3 || periods[0].Period != "2022-12-31" || periods[1].Period != "2021-12-31" || periods[2].Period != "2020-12-31" {
+		t.Fatalf("got periods %+v, want the three dates latest first", periods)
+	}
+	if items := periods[0].Items; len(items) != 1 || items["营业收入"] != 2000 {
+		t.Fatalf("got items %v of 2022-12-31", items)
+	}
+	if periods[2].Items["净利润"] != 12.5 {
+		t.Fatalf("got items %v of 2020-12-31", periods[2].Items)
+	}
+}
+
+func TestGetFinancialStatements(t *testing.T) {
+	source := replaytest.NewSource(t, filepath.Join("testdata", "statement.json"))
+	f, err := source.GetFinancialStatements(&cninfo.Stock{Code: "000001"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if periods := f.Periods(); len(periods) != 2 || periods[0] != "2022-12-31" || periods[1] != "2021-12-31" {
+		t.Fatalf("got periods %v, want 2022-12-31 and 2021-12-31", periods)
+	}
+	m := f.MainIndicators["2022-12-31"]
+	if m.EPS != 2.2 || m.BVPS != 20.08 || m.ROE != 12.36 || m.DebtRatio != 91.6 || len(m.Items) != 4 {
+		t.Fatalf("got main indicators %+v", m)
+	}
+	b := f.BalanceSheets["2021-12-31"]
+	if b.Cash != 325142000000 || b.TotalAssets != 4921380000000 || b.TotalLiabilities != 4526080000000 || b.TotalEquity != 395300000000 {
+		t.Fatalf("got balance sheet %+v", b)
+	}
+	i := f.IncomeStatements["2022-12-31"]
+	if i.Revenue != 179895000000 || i.OperatingProfit != 58354000000 || i.NetProfit != 45516000000 || i.NetProfitAttributable != 45516000000 || i.EPS != 2.2 {
+		t.Fatalf("got income statement %+v", i)
+	}
+	c := f.CashFlowStatements["2021-12-31"]
+	if c.OperatingCashFlow != -137100000000 || c.NetCashFlow != -5000000000 || c.InvestingCashFlow != 0 {
+		t.Fatalf("got cash flow statement %+v", c)
+	}
+}
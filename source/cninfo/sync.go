@@ -0,0 +1,147 @@
+package cninfo
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+)
+
+// aSharePlates split the A-share market by exchange.
+var aSharePlates = []Plate{PlateSZ, PlateSH, PlateBSE}
+
+func (s *Source) GetMarketAnnouncements(market Market, category Category, start, end time.Time) ([]*Announcement, error) {
+	return s.GetMarketAnnouncementsContext(context.Background(), market, category, start, end)
+}
+
+// GetMarketAnnouncementsContext returns the announcements of category of
+// every stock of market published between start and end, oldest first.
+func (s *Source) GetMarketAnnouncementsContext(ctx context.Context, market Market, category Category, start, end time.Time) ([]*Announcement, error) {
+	return s.GetAnnouncementsContext(ctx, &SearchQuery{Market: market, Category: category}, start, end)
+}
+
+func (s *Source) GetAnnouncements(q *SearchQuery, start, end time.Time) ([]*Announcement, error) {
+	return s.GetAnnouncementsContext(context.Background(), q, start, end)
+}
+
+// GetAnnouncementsContext returns every announcement matching q published
+// between start and end, oldest first. The dates and sort of q are ignored.
+// Unlike Search, windows too large for cninfo to page through are split.
+func (s *Source) GetAnnouncementsContext(ctx context.Context, q *SearchQuery, start, end time.Time) ([]*Announcement, error) {
+	if start.After(end) {
+		return nil, errors.New("start time must be before end time")
+	}
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	r := q.request()
+	r.SortName, r.SortType = "", ""
+	if q.Market == MarketHK {
+		r.Category = ""
+	}
+	announcements, err := s.queryAnnouncementWindow(ctx, r, start, end)
+	plates := aSharePlates
+	if len(q.Plates) > 0 {
+		plates = q.Plates
+	}
+	if errors.Is(err, errIncomplete) && q.Market.column() == string(MarketAShare) && len(plates) > 1 {
+		// A single day of the whole market can be more than cninfo lets
+		// us page through in the reporting season, query by plate then.
+		announcements, err = nil, nil
+		for _, plate := range plates {
+			p := *r
+			p.Plate = string(plate)
+			a, err := s.queryAnnouncementWindow(ctx, &p, start, end)
+			if err != nil {
+				return nil, err
+			}
+			announcements = append(announcements, a...)
+		}
+		announcements = uniqueAnnouncements(announcements)
+		sort.SliceStable(announcements, func(i, j int) bool {
+			return announcements[i].AnnouncementTime < announcements[j].AnnouncementTime
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	if q.Market == MarketHK {
+		return matchHKCategory(q.Category, announcements), nil
+	}
+	return announcements, nil
+}
+
+// SyncCursor is the progress of a market-wide sync of the announcements of
+// a category: every announcement published before Date has been synced.
+// Plates and Trades, if set, restrict the sync to stocks of them.
+type SyncCursor struct {
+	Market   Market   `json:"market"`
+	Category Category `json:"category"`
+	Plates   []Plate  `json:"plates,omitempty"`
+	Trades   []Trade  `json:"trades,omitempty"`
+	Date     string   `json:"date"`
+}
+
+// NewSyncCursor returns a cursor syncing from the day of t.
+func NewSyncCursor(market Market, category Category, t time.Time) *SyncCursor {
+	return &SyncCursor{Market: market, Category: category, Date: formatDate(t)}
+}
+
+// Matches reports whether c syncs the same announcements as a cursor of
+// market, category, plates and trades.
+func (c *SyncCursor) Matches(market Market, category Category, plates []Plate, trades []Trade) bool {
+	if c.Market != market || c.Category != category || len(c.Plates) != len(plates) || len(c.Trades) != len(trades) {
+		return false
+	}
+	for i := range plates {
+		if c.Plates[i] != plates[i] {
+			return false
+		}
+	}
+	for i := range trades {
+		if c.Trades[i] != trades[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Time returns the date of c, or the zero time if not set.
+func (c *SyncCursor) Time() (time.Time, error) {
+	return ParseDate(c.Date)
+}
+
+// Sync returns the announcements of the market and category of cursor
+// published from its date until the day of until, and on success moves
+// cursor to that day. The day of the cursor is queried again, as cninfo
+// may have published more on it since the last sync, so callers must
+// expect to see some announcements twice.
+func (s *Source) Sync(ctx context.Context, cursor *SyncCursor, until time.Time) ([]*Announcement, error) {
+	start, err := cursor.Time()
+	if err != nil {
+		return nil, err
+	}
+	if start.IsZero() {
+		return nil, errors.New("sync cursor has no date")
+	}
+	end := Date(until)
+	if start.After(end) {
+		start = end
+	}
+	q := &SearchQuery{Market: cursor.Market, Category: cursor.Category, Plates: cursor.Plates, Trades: cursor.Trades}
+	announcements, err := s.GetAnnouncementsContext(ctx, q, start, end)
+	if err != nil {
+		return nil, err
+	}
+	cursor.Date = formatDate(end)
+	return announcements, nil
+}
+
+// GroupAnnouncements groups announcements by SecCode, keeping their order.
+func GroupAnnouncements(announcements []*Announcement) map[string][]*Announcement {
+	m := make(map[string][]*Announcement)
+	for _, announcement := range announcements {
+		m[announcement.SecCode] = append(m[announcement.SecCode], announcement)
+	}
+	return m
+}
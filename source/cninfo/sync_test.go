@@ -0,0 +1,108 @@
+package cninfo_test
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chamzzzzzz/financial/source/cninfo"
+	"github.com/chamzzzzzz/financial/source/cninfo/cninfotest"
+)
+
+func TestSync(t *testing.T) {
+	day := func(d int) int64 {
+		return time.Date(2023, 4, d, 18, 0, 0, 0, cninfo.Location).UnixMilli()
+	}
+	server := cninfotest.NewServer(&cninfotest.Dataset{
+		Announcements: map[cninfo.Category][]*cninfo.Announcement{
+			cninfo.CategoryAnnualReport: {
+				{AnnouncementID: "1", SecCode: "000001", OrgID: "gssz0000001", AnnouncementTitle: "2022年年度报告", AnnouncementTime: day(10)},
+				{AnnouncementID: "2", SecCode: "600000", OrgID: "gssh0600000", AnnouncementTitle: "2022年年度报告", AnnouncementTime: day(20)},
+				{AnnouncementID: "3", SecCode: "000001", OrgID: "gssz0000001", AnnouncementTitle: "2022年年度报告摘要", AnnouncementTime: day(21)},
+				{AnnouncementID: "4", SecCode: "000002", OrgID: "gssz0000002", AnnouncementTitle: "2022年年度报告", AnnouncementTime: day(25)},
+			},
+		},
+	})
+	defer server.Close()
+
+	cursor := cninfo.NewSyncCursor(cninfo.MarketAShare, cninfo.CategoryAnnualReport, time.UnixMilli(day(20)))
+	announcements, err := server.Source().Sync(context.Background(), cursor, time.UnixMilli(day(22)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(announcements) != 2 || announcements[0].AnnouncementID != "2" || announcements[1].AnnouncementID != "3" {
+		t.Fatalf("got announcements %+v, want 2 and 3", announcements)
+	}
+	if cursor.Date != "2023-04-22" {
+		t.Fatalf("got cursor date %s, want 2023-04-22", cursor.Date)
+	}
+	grouped := cninfo.GroupAnnouncements(announcements)
+	if len(grouped) != 2 || len(grouped["000001"]) != 1 || len(grouped["600000"]) != 1 {
+		t.Fatalf("got grouped announcements %v", grouped)
+	}
+
+	announcements, err = server.Source().Sync(context.Background(), cursor, time.UnixMilli(day(30)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(announcements) != 1 || announcements[0].AnnouncementID != "4" {
+		t.Fatalf("got announcements %+v, want 4", announcements)
+	}
+
+	if _, err := server.Source().Sync(context.Background(), &cninfo.SyncCursor{Market: cninfo.MarketAShare}, time.Now()); err == nil {
+		t.Fatal("got no error for cursor without date")
+	}
+}
+
+func TestSyncPlatesAndTrades(t *testing.T) {
+	day := time.Date(2023, 4, 20, 18, 0, 0, 0, cninfo.Location)
+	server := cninfotest.NewServer(&cninfotest.Dataset{
+		Announcements: map[cninfo.Category][]*cninfo.Announcement{
+			cninfo.CategoryAnnualReport: {
+				{AnnouncementID: "1", SecCode: "000001", AnnouncementTitle: "2022年年度报告", AnnouncementTime: day.UnixMilli()},
+				{AnnouncementID: "2", SecCode: "300750", AnnouncementTitle: "2022年年度报告", AnnouncementTime: day.UnixMilli()},
+				{AnnouncementID: "3", SecCode: "600000", AnnouncementTitle: "2022年年度报告", AnnouncementTime: day.UnixMilli()},
+				{AnnouncementID: "4", SecCode: "688981", AnnouncementTitle: "2022年年度报告", AnnouncementTime: day.UnixMilli()},
+			},
+		},
+		Trades: map[string]cninfo.Trade{
+			"000001": cninfo.TradeFinance,
+			"300750": cninfo.TradeManufacturing,
+			"600000": cninfo.TradeFinance,
+			"688981": cninfo.TradeManufacturing,
+		},
+	})
+	defer server.Close()
+
+	tests := []struct {
+		plates []cninfo.Plate
+		trades []cninfo.Trade
+		want   string
+	}{
+		{nil, nil, "1,2,3,4"},
+		{[]cninfo.Plate{cninfo.PlateChiNext}, nil, "2"},
+		{[]cninfo.Plate{cninfo.PlateSH}, nil, "3,4"},
+		{[]cninfo.Plate{cninfo.PlateSZMain, cninfo.PlateSTAR}, nil, "1,4"},
+		{nil, []cninfo.Trade{cninfo.TradeFinance}, "1,3"},
+		{[]cninfo.Plate{cninfo.PlateSH}, []cninfo.Trade{cninfo.TradeManufacturing}, "4"},
+		{[]cninfo.Plate{cninfo.PlateBSE}, nil, ""},
+	}
+	for _, tt := range tests {
+		cursor := cninfo.NewSyncCursor(cninfo.MarketAShare, cninfo.CategoryAnnualReport, day.AddDate(0, 0, -1))
+		cursor.Plates, cursor.Trades = tt.plates, tt.trades
+		announcements, err := server.Source().Sync(context.Background(), cursor, day.AddDate(0, 0, 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, announcement := range announcements {
+			ids = append(ids, announcement.AnnouncementID)
+		}
+		sort.Strings(ids)
+		if got := strings.Join(ids, ","); got != tt.want {
+			t.Errorf("plates %v trades %v: got announcements %s, want %s", tt.plates, tt.trades, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package cninfo
+
+import (
+	"fmt"
+	"time"
+)
+
+// Location is the exchange time zone all cninfo dates are given in.
+var Location = loadLocation()
+
+func loadLocation() *time.Location {
+	if loc, err := time.LoadLocation("Asia/Shanghai"); err == nil {
+		return loc
+	}
+	return time.FixedZone("CST", 8*3600)
+}
+
+// Now returns the current time in Location.
+func Now() time.Time {
+	return time.Now().In(Location)
+}
+
+// Today returns the start of the current day in Location.
+func Today() time.Time {
+	return Date(Now())
+}
+
+// Date returns the start of the day of t in Location.
+func Date(t time.Time) time.Time {
+	y, m, d := t.In(Location).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, Location)
+}
+
+func formatDate(t time.Time) string {
+	return t.In(Location).Format("2006-01-02")
+}
+
+// ParseDate parses a cninfo date such as "2023-07-12" in Location. Empty and
+// "--" dates mean the date is not set yet and return the zero time without
+// error.
+func ParseDate(s string) (time.Time, error) {
+	if s == "" || s == "--" {
+		return time.Time{}, nil
+	}
+	for _, layout := range []string{"2006-01-02", "2006-01-02 15:04:05", "20060102"} {
+		if t, err := time.ParseInLocation(layout, s, Location); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+// Time returns the publish time of the announcement in Location.
+func (a *Announcement) Time() time.Time {
+	return time.UnixMilli(a.AnnouncementTime).In(Location)
+}
+
+// RecordTime returns the record date, or the zero time if not set.
+func (r *DividendRecord) RecordTime() (time.Time, error) {
+	return ParseDate(r.RecordDate)
+}
+
+// ExDividendTime returns the ex-dividend date, or the zero time if not set.
+func (r *DividendRecord) ExDividendTime() (time.Time, error) {
+	return ParseDate(r.ExDividendDate)
+}
+
+// PayTime returns the pay date, or the zero time if not set.
+func (r *DividendRecord) PayTime() (time.Time, error) {
+	return ParseDate(r.PayDate)
+}
@@ -0,0 +1,283 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/chamzzzzzz/financial/source/cninfo"
+)
+
+// ErrNotSupported is returned by providers for requests they cannot serve,
+// e.g. an exchange provider asked about a stock listed elsewhere.
+var ErrNotSupported = errors.New("not supported")
+
+type failover []Provider
+
+// Failover returns a provider that sends every request to providers in
+// order until one succeeds. Stock lists are the exception: the exchange
+// providers list only their own stocks, so the lists of all providers are
+// merged.
+func Failover(providers ...Provider) Provider {
+	if len(providers) == 1 {
+		return providers[0]
+	}
+	return failover(providers)
+}
+
+func (f failover) try(ctx context.Context, fn func(p Provider) error) error {
+	var errs []error
+	for i, p := range f {
+		err := fn(p)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		errs = append(errs, fmt.Errorf("provider %d: %w", i, err))
+	}
+	return joinErrors(errs)
+}
+
+// multiError is the error of every provider of a failover.
+type multiError []error
+
+func (m multiError) Error() string {
+	var s []string
+	for _, err := range m {
+		s = append(s, err.Error())
+	}
+	return strings.Join(s, "; ")
+}
+
+func (m multiError) Is(target error) bool {
+	for _, err := range m {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m multiError) As(target any) bool {
+	for _, err := range m {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// joinErrors joins the errors of the providers. ErrNotSupported is only
+// returned when no provider supports the request, so that callers skipping
+// unsupported requests do not miss real failures.
+func joinErrors(errs []error) error {
+	if len(errs) == 0 {
+		return errors.New("source: no provider")
+	}
+	var failed multiError
+	for _, err := range errs {
+		if !errors.Is(err, ErrNotSupported) {
+			failed = append(failed, err)
+		}
+	}
+	if len(failed) == 0 {
+		failed = errs
+	}
+	if len(failed) == 1 {
+		return failed[0]
+	}
+	return failed
+}
+
+// GetStockListContext merges the lists of the providers by code, keeping
+// the order of the first provider listing a stock and filling its empty
+// fields, e.g. the OrgID the exchanges lack, from the later ones. It fails
+// only if no provider returns a list.
+func (f failover) GetStockListContext(ctx context.Context) ([]*cninfo.Stock, error) {
+	var (
+		stocks []*cninfo.Stock
+		errs   []error
+		ok     bool
+	)
+	index := make(map[string]*cninfo.Stock)
+	for i, p := range f {
+		list, err := p.GetStockListContext(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			errs = append(errs, fmt.Errorf("provider %d: %w", i, err))
+			continue
+		}
+		ok = true
+		for _, stock := range list {
+			merged, found := index[stock.Code]
+			if !found {
+				merged = &cninfo.Stock{}
+				*merged = *stock
+				index[stock.Code] = merged
+				stocks = append(stocks, merged)
+				continue
+			}
+			fill(&merged.Pinyin, stock.Pinyin)
+			fill(&merged.Category, stock.Category)
+			fill(&merged.OrgID, stock.OrgID)
+			fill(&merged.Zwjc, stock.Zwjc)
+			if merged.Market == "" {
+				merged.Market = stock.Market
+			}
+		}
+	}
+	if !ok {
+		return nil, joinErrors(errs)
+	}
+	return stocks, nil
+}
+
+func fill(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+}
+
+func (f failover) GetPeriodicReportAnnouncementsContext(ctx context.Context, stock *cninfo.Stock, category cninfo.Category, start, end time.Time) (announcements []*cninfo.Announcement, err error) {
+	err = f.try(ctx, func(p Provider) (err error) {
+		announcements, err = p.GetPeriodicReportAnnouncementsContext(ctx, stock, category, start, end)
+		return err
+	})
+	return announcements, err
+}
+
+func (f failover) GetDividendRecordsContext(ctx context.Context, stock *cninfo.Stock) (records []*cninfo.DividendRecord, err error) {
+	err = f.try(ctx, func(p Provider) (err error) {
+		records, err = p.GetDividendRecordsContext(ctx, stock)
+		return err
+	})
+	return records, err
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	return n, err
+}
+
+// DownloadAdjunct fails over only while nothing has been written to w.
+func (f failover) DownloadAdjunct(ctx context.Context, announcement *cninfo.Announcement, w io.Writer) error {
+	cw := &countWriter{w: w}
+	var errs []error
+	for i, p := range f {
+		err := p.DownloadAdjunct(ctx, announcement, cw)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil || cw.n > 0 {
+			return err
+		}
+		errs = append(errs, fmt.Errorf("provider %d: %w", i, err))
+	}
+	return joinErrors(errs)
+}
+
+// DownloadAdjunctFile downloads to file with the first provider that
+// succeeds, using the resumable file download of the ones that have it. It
+// fails over only while the part file left to resume has not grown, so a
+// download is not resumed from another provider's bytes.
+func (f failover) DownloadAdjunctFile(ctx context.Context, announcement *cninfo.Announcement, file string) error {
+	var errs []error
+	for i, p := range f {
+		size := partSize(file)
+		err := DownloadAdjunctFile(ctx, p, announcement, file)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil || partSize(file) > size {
+			return err
+		}
+		errs = append(errs, fmt.Errorf("provider %d: %w", i, err))
+	}
+	return joinErrors(errs)
+}
+
+// partSize returns the size of the part file cninfo.Source resumes a
+// download of file from, 0 if there is none.
+func partSize(file string) int64 {
+	fi, err := os.Stat(file + ".part")
+	if err != nil {
+		return 0
+	}
+	return fi.Size()
+}
+
+// OpenFailover opens the providers named in the comma separated list names
+// and returns their Failover. Providers already opened by the caller can
+// be passed in opened by name to be used instead of opening them again.
+func OpenFailover(names string, config *Config, opened map[string]Provider) (Provider, error) {
+	var providers []Provider
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		p, ok := opened[name]
+		if !ok {
+			var err error
+			if p, err = Open(name, config); err != nil {
+				return nil, err
+			}
+		}
+		providers = append(providers, p)
+	}
+	if len(providers) == 0 {
+		return nil, errors.New("source: no provider")
+	}
+	return Failover(providers...), nil
+}
+
+// announcementKey identifies an announcement across providers, which use
+// different ids, by its publish date and its title without the "name："
+// prefix some providers add.
+func announcementKey(a *cninfo.Announcement) string {
+	title := a.AnnouncementTitle
+	for _, sep := range []string{"：", ":"} {
+		if i := strings.LastIndex(title, sep); i >= 0 {
+			title = title[i+len(sep):]
+		}
+	}
+	title = strings.Join(strings.Fields(title), "")
+	return cninfo.Date(a.Time()).Format("2006-01-02") + " " + title
+}
+
+// Compare cross-checks the announcements of the same query from two
+// providers and returns the ones only a has and the ones only b has.
+func Compare(a, b []*cninfo.Announcement) (onlyA, onlyB []*cninfo.Announcement) {
+	return subtract(a, b), subtract(b, a)
+}
+
+func subtract(a, b []*cninfo.Announcement) []*cninfo.Announcement {
+	keys := make(map[string]int)
+	for _, announcement := range b {
+		keys[announcementKey(announcement)]++
+	}
+	var r []*cninfo.Announcement
+	for _, announcement := range a {
+		key := announcementKey(announcement)
+		if keys[key] > 0 {
+			keys[key]--
+			continue
+		}
+		r = append(r, announcement)
+	}
+	return r
+}
@@ -0,0 +1,126 @@
+// Package source defines the provider-neutral interface the commands use to
+// get stock lists, periodic report announcements, dividend records and
+// adjunct files, and a registry to pick a provider by name.
+//
+// The cninfo provider is always registered. Other providers register
+// themselves when imported, like database/sql drivers.
+package source
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/chamzzzzzz/financial/source/cninfo"
+)
+
+// Provider is a source of disclosure data. Results are normalized into the
+// cninfo types.
+type Provider interface {
+	GetStockListContext(ctx context.Context) ([]*cninfo.Stock, error)
+	GetPeriodicReportAnnouncementsContext(ctx context.Context, stock *cninfo.Stock, category cninfo.Category, start, end time.Time) ([]*cninfo.Announcement, error)
+	GetDividendRecordsContext(ctx context.Context, stock *cninfo.Stock) ([]*cninfo.DividendRecord, error)
+	DownloadAdjunct(ctx context.Context, announcement *cninfo.Announcement, w io.Writer) error
+}
+
+var _ Provider = (*cninfo.Source)(nil)
+
+// Config configures a provider. Zero fields use the provider defaults.
+type Config struct {
+	Client   *http.Client
+	Timeout  time.Duration
+	Interval time.Duration
+	CacheDir string
+}
+
+// Factory returns a provider for config.
+type Factory func(config *Config) (Provider, error)
+
+var (
+	mu        sync.RWMutex
+	factories = make(map[string]Factory)
+)
+
+// Register makes a provider available by name. It panics if the name is
+// registered twice.
+func Register(name string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := factories[name]; ok {
+		panic("source: Register called twice for provider " + name)
+	}
+	factories[name] = factory
+}
+
+// Providers returns the sorted names of the registered providers.
+func Providers() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	var names []string
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Open returns the provider registered by name. A nil config uses the
+// provider defaults.
+func Open(name string, config *Config) (Provider, error) {
+	mu.RLock()
+	factory, ok := factories[name]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("source: unknown provider %q", name)
+	}
+	if config == nil {
+		config = &Config{}
+	}
+	return factory(config)
+}
+
+func init() {
+	Register("cninfo", func(config *Config) (Provider, error) {
+		s := &cninfo.Source{
+			Client:  config.Client,
+			Timeout: config.Timeout,
+		}
+		if config.Interval > 0 {
+			s.Limiter = cninfo.NewRateLimiter(config.Interval, 1)
+		}
+		if config.CacheDir != "" {
+			s.Cache = cninfo.NewCache(config.CacheDir)
+		}
+		return s, nil
+	})
+}
+
+// DownloadAdjunctFile downloads the adjunct file of announcement to file. It
+// uses the provider's own file download if it has one, e.g. the resumable
+// one of cninfo.Source, and writes through a temporary file otherwise.
+func DownloadAdjunctFile(ctx context.Context, p Provider, announcement *cninfo.Announcement, file string) error {
+	if d, ok := p.(interface {
+		DownloadAdjunctFile(ctx context.Context, announcement *cninfo.Announcement, file string) error
+	}); ok {
+		return d.DownloadAdjunctFile(ctx, announcement, file)
+	}
+	tmp := file + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	err = p.DownloadAdjunct(ctx, announcement, f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, file)
+}
@@ -0,0 +1,162 @@
+package source_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chamzzzzzz/financial/source"
+	"github.com/chamzzzzzz/financial/source/cninfo"
+	"github.com/chamzzzzzz/financial/source/cninfo/cninfotest"
+)
+
+func TestOpen(t *testing.T) {
+	server := cninfotest.NewServer(&cninfotest.Dataset{
+		Stocks: []*cninfo.Stock{{Code: "000001", OrgID: "gssz0000001", Zwjc: "平安银行"}},
+	})
+	defer server.Close()
+
+	p, err := source.Open("cninfo", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := p.(*cninfo.Source)
+	s.Client = server.Client()
+	s.BaseURL = server.URL
+	stocks, err := p.GetStockListContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stocks) != 1 || stocks[0].Code != "000001" {
+		t.Fatalf("got %v, want 000001", stocks)
+	}
+
+	if _, err := source.Open("unknown", nil); err == nil {
+		t.Fatal("open unknown provider, want error")
+	}
+}
+
+type stub struct {
+	stocks        []*cninfo.Stock
+	announcements []*cninfo.Announcement
+	adjunct       []byte
+	err           error
+}
+
+func (s *stub) GetStockListContext(ctx context.Context) ([]*cninfo.Stock, error) {
+	return s.stocks, s.err
+}
+
+func (s *stub) GetPeriodicReportAnnouncementsContext(ctx context.Context, stock *cninfo.Stock, category cninfo.Category, start, end time.Time) ([]*cninfo.Announcement, error) {
+	return s.announcements, s.err
+}
+
+func (s *stub) GetDividendRecordsContext(ctx context.Context, stock *cninfo.Stock) ([]*cninfo.DividendRecord, error) {
+	return nil, s.err
+}
+
+func (s *stub) DownloadAdjunct(ctx context.Context, announcement *cninfo.Announcement, w io.Writer) error {
+	if s.err != nil {
+		return s.err
+	}
+	_, err := w.Write(s.adjunct)
+	return err
+}
+
+// fileStub has a resumable file download that leaves part of the file
+// behind when it fails.
+type fileStub struct {
+	stub
+	part []byte
+}
+
+func (s *fileStub) DownloadAdjunctFile(ctx context.Context, announcement *cninfo.Announcement, file string) error {
+	if len(s.part) > 0 {
+		if err := os.WriteFile(file+".part", s.part, 0644); err != nil {
+			return err
+		}
+	}
+	return s.err
+}
+
+func TestFailover(t *testing.T) {
+	day := time.Date(2023, 3, 9, 0, 0, 0, 0, cninfo.Location).UnixMilli()
+	want := []*cninfo.Announcement{{AnnouncementTitle: "平安银行：2022年年度报告", AnnouncementTime: day}}
+	p := source.Failover(
+		&stub{err: fmt.Errorf("szse: %w", source.ErrNotSupported)},
+		&stub{err: errors.New("throttled")},
+		&stub{announcements: want},
+	)
+	got, err := p.GetPeriodicReportAnnouncementsContext(context.Background(), &cninfo.Stock{Code: "000001"}, cninfo.CategoryAnnualReport, time.Time{}, time.Now())
+	if err != nil || len(got) != 1 {
+		t.Fatalf("got %v, %v, want the announcement of the last provider", got, err)
+	}
+
+	_, err = source.Failover(&stub{err: source.ErrNotSupported}, &stub{err: errors.New("throttled")}).GetDividendRecordsContext(context.Background(), &cninfo.Stock{})
+	if err == nil || errors.Is(err, source.ErrNotSupported) {
+		t.Fatalf("got %v, want the throttled error only", err)
+	}
+
+	onlyA, onlyB := source.Compare(want, []*cninfo.Announcement{
+		{AnnouncementTitle: "2022年年度报告", AnnouncementTime: day + 3600*1000},
+		{AnnouncementTitle: "2022年年度报告摘要", AnnouncementTime: day},
+	})
+	if len(onlyA) != 0 || len(onlyB) != 1 || onlyB[0].AnnouncementTitle != "2022年年度报告摘要" {
+		t.Fatalf("got %v and %v, want the summary only in b", onlyA, onlyB)
+	}
+}
+
+func TestFailoverStockList(t *testing.T) {
+	p := source.Failover(
+		&stub{stocks: []*cninfo.Stock{{Code: "000001", Zwjc: "平安银行"}}},
+		&stub{err: errors.New("throttled")},
+		&stub{stocks: []*cninfo.Stock{{Code: "600000", Zwjc: "浦发银行", OrgID: "gssh0600000"}, {Code: "000001", OrgID: "gssz0000001"}}},
+	)
+	stocks, err := p.GetStockListContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stocks) != 2 || stocks[0].Code != "000001" || stocks[0].Zwjc != "平安银行" || stocks[0].OrgID != "gssz0000001" || stocks[1].Code != "600000" {
+		t.Fatalf("got %+v, want both exchanges merged", stocks)
+	}
+
+	if _, err := source.Failover(&stub{err: errors.New("throttled")}, &stub{err: errors.New("throttled")}).GetStockListContext(context.Background()); err == nil {
+		t.Fatal("got no error, want the error of every provider")
+	}
+}
+
+func TestFailoverDownloadAdjunctFile(t *testing.T) {
+	announcement := &cninfo.Announcement{AnnouncementID: "1", AdjunctURL: "finalpage/2023-03-09/1.PDF"}
+	file := filepath.Join(t.TempDir(), "1.pdf")
+	p := source.Failover(
+		&fileStub{stub: stub{err: errors.New("throttled")}},
+		&stub{err: source.ErrNotSupported},
+		&stub{adjunct: []byte("%PDF-1.4")},
+	)
+	if err := source.DownloadAdjunctFile(context.Background(), p, announcement, file); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := os.ReadFile(file); err != nil || string(b) != "%PDF-1.4" {
+		t.Fatalf("got %q, %v, want the adjunct of the last provider", b, err)
+	}
+
+	file = filepath.Join(t.TempDir(), "1.pdf")
+	p = source.Failover(
+		&fileStub{stub: stub{err: errors.New("connection reset")}, part: []byte("%PDF-")},
+		&stub{adjunct: []byte("%PDF-1.4")},
+	)
+	if err := source.DownloadAdjunctFile(context.Background(), p, announcement, file); err == nil {
+		t.Fatal("got no error, want the one of the provider that left a part file")
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Fatalf("got %v, want the file not downloaded by the next provider", err)
+	}
+	if b, err := os.ReadFile(file + ".part"); err != nil || string(b) != "%PDF-" {
+		t.Fatalf("got part %q, %v, want it kept to resume", b, err)
+	}
+}
@@ -0,0 +1,377 @@
+// Package sse gets stock lists and periodic report announcements directly
+// from the Shanghai Stock Exchange query API. Results are normalized into
+// the cninfo types and the package registers the "sse" provider.
+package sse
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chamzzzzzz/financial/source"
+	"github.com/chamzzzzzz/financial/source/cninfo"
+)
+
+const (
+	DefaultBaseURL   = "https://query.sse.com.cn"
+	DefaultStaticURL = "https://static.sse.com.cn"
+	DefaultReferer   = "https://www.sse.com.cn/"
+)
+
+// DefaultRateLimiter is used by every Source without a Limiter.
+var DefaultRateLimiter = cninfo.NewRateLimiter(200*time.Millisecond, 5)
+
+var reportTypes = map[cninfo.Category]string{
+	cninfo.CategoryAnnualReport:       "YEARLY",
+	cninfo.CategorySemiAnnualReport:   "QUATER2",
+	cninfo.CategoryFirstQuarterReport: "QUATER1",
+	cninfo.CategoryThirdQuarterReport: "QUATER3",
+}
+
+// stockTypes are the boards of the stock list: main board A shares and the
+// STAR market.
+var stockTypes = []string{"1", "8"}
+
+// Source is a client of the SSE query API. The zero value is ready to use.
+type Source struct {
+	// Client is used to send requests. If nil, http.DefaultClient is used.
+	Client *http.Client
+
+	// BaseURL is the root of the query endpoints. If empty, DefaultBaseURL
+	// is used.
+	BaseURL string
+
+	// StaticURL is the root of the adjunct files. If empty,
+	// DefaultStaticURL is used.
+	StaticURL string
+
+	// UserAgent is sent with every request. If empty,
+	// cninfo.DefaultUserAgent is used.
+	UserAgent string
+
+	// Timeout limits each request. Zero means no limit.
+	Timeout time.Duration
+
+	// Limiter throttles the requests. If nil, DefaultRateLimiter is used.
+	Limiter *cninfo.RateLimiter
+
+	// Retry decides which failed requests are sent again. If nil,
+	// cninfo.DefaultRetryPolicy is used.
+	Retry *cninfo.RetryPolicy
+}
+
+var _ source.Provider = (*Source)(nil)
+
+func init() {
+	source.Register("sse", func(config *source.Config) (source.Provider, error) {
+		s := &Source{
+			Client:  config.Client,
+			Timeout: config.Timeout,
+		}
+		if config.Interval > 0 {
+			s.Limiter = cninfo.NewRateLimiter(config.Interval, 1)
+		}
+		return s, nil
+	})
+}
+
+func (s *Source) baseURL() string {
+	if s.BaseURL != "" {
+		return strings.TrimSuffix(s.BaseURL, "/")
+	}
+	return DefaultBaseURL
+}
+
+func (s *Source) staticURL() string {
+	if s.StaticURL != "" {
+		return strings.TrimSuffix(s.StaticURL, "/")
+	}
+	return DefaultStaticURL
+}
+
+func (s *Source) userAgent() string {
+	if s.UserAgent != "" {
+		return s.UserAgent
+	}
+	return cninfo.DefaultUserAgent
+}
+
+func (s *Source) limiter() *cninfo.RateLimiter {
+	if s.Limiter != nil {
+		return s.Limiter
+	}
+	return DefaultRateLimiter
+}
+
+func (s *Source) retry() *cninfo.RetryPolicy {
+	if s.Retry != nil {
+		return s.Retry
+	}
+	return cninfo.DefaultRetryPolicy
+}
+
+// Supports reports whether code is listed on SSE.
+func Supports(code string) bool {
+	return len(code) == 6 && code[0] == '6'
+}
+
+// do gets path with query. The api refuses requests without a sse.com.cn
+// referer.
+func (s *Source) do(ctx context.Context, path string, query url.Values, v any) error {
+	return s.retry().Do(ctx, func() error {
+		return s.send(ctx, path, query, v)
+	})
+}
+
+// send sends one attempt of a request of do.
+func (s *Source) send(ctx context.Context, path string, query url.Values, v any) error {
+	if err := s.limiter().Wait(ctx); err != nil {
+		return err
+	}
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", s.baseURL()+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json, text/javascript, */*")
+	req.Header.Set("User-Agent", s.userAgent())
+	req.Header.Set("Referer", DefaultReferer)
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("sse: %w", &cninfo.StatusError{StatusCode: resp.StatusCode})
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("sse: %w", &cninfo.SchemaError{URL: req.URL.String(), Err: err})
+	}
+	return nil
+}
+
+type PageHelp struct {
+	PageNo    int `json:"pageNo"`
+	PageSize  int `json:"pageSize"`
+	PageCount int `json:"pageCount"`
+	Total     int `json:"total"`
+}
+
+func pageQuery(query url.Values, pageNo, pageSize int) url.Values {
+	query.Set("isPagination", "true")
+	query.Set("pageHelp.pageSize", strconv.Itoa(pageSize))
+	query.Set("pageHelp.pageNo", strconv.Itoa(pageNo))
+	query.Set("pageHelp.beginPage", strconv.Itoa(pageNo))
+	query.Set("pageHelp.endPage", strconv.Itoa(pageNo))
+	query.Set("pageHelp.cacheSize", "1")
+	return query
+}
+
+type StockListResponse struct {
+	Result []struct {
+		Code string `json:"A_STOCK_CODE"`
+		Name string `json:"COMPANY_ABBR"`
+	} `json:"result"`
+	PageHelp PageHelp `json:"pageHelp"`
+}
+
+func (s *Source) RequestStockList(stockType string, pageNo int) (*StockListResponse, error) {
+	return s.RequestStockListContext(context.Background(), stockType, pageNo)
+}
+
+func (s *Source) RequestStockListContext(ctx context.Context, stockType string, pageNo int) (*StockListResponse, error) {
+	query := pageQuery(url.Values{
+		"sqlId":          {"COMMON_SSE_CP_GPJCTPZ_GPLB_GP_L"},
+		"STOCK_TYPE":     {stockType},
+		"COMPANY_STATUS": {"2,4,5,7,8"},
+		"type":           {"inParams"},
+	}, pageNo, 500)
+	p := &StockListResponse{}
+	if err := s.do(ctx, "/sseQuery/commonQuery.do", query, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *Source) GetStockList() ([]*cninfo.Stock, error) {
+	return s.GetStockListContext(context.Background())
+}
+
+func (s *Source) GetStockListContext(ctx context.Context) ([]*cninfo.Stock, error) {
+	var stocks []*cninfo.Stock
+	for _, stockType := range stockTypes {
+		for pageNo := 1; ; pageNo++ {
+			p, err := s.RequestStockListContext(ctx, stockType, pageNo)
+			if err != nil {
+				return nil, err
+			}
+			for _, result := range p.Result {
+				stocks = append(stocks, &cninfo.Stock{
+					Code:     result.Code,
+					Category: "A股",
+					Zwjc:     strings.TrimSpace(result.Name),
+					Market:   cninfo.MarketAShare,
+				})
+			}
+			if pageNo >= p.PageHelp.PageCount || len(p.Result) == 0 {
+				break
+			}
+		}
+	}
+	return stocks, nil
+}
+
+type Bulletin struct {
+	SecurityCode string `json:"SECURITY_CODE"`
+	SecurityName string `json:"SECURITY_NAME"`
+	Title        string `json:"TITLE"`
+	URL          string `json:"URL"`
+	SSEDate      string `json:"SSEDATE"`
+	BulletinType string `json:"BULLETIN_TYPE"`
+	BulletinYear string `json:"BULLETIN_YEAR"`
+}
+
+type BulletinResponse struct {
+	Result   []*Bulletin `json:"result"`
+	PageHelp PageHelp    `json:"pageHelp"`
+}
+
+type BulletinQuery struct {
+	Code       string
+	ReportType string
+	BeginDate  string
+	EndDate    string
+	PageNo     int
+	PageSize   int
+}
+
+func (s *Source) RequestCompanyBulletin(q *BulletinQuery) (*BulletinResponse, error) {
+	return s.RequestCompanyBulletinContext(context.Background(), q)
+}
+
+func (s *Source) RequestCompanyBulletinContext(ctx context.Context, q *BulletinQuery) (*BulletinResponse, error) {
+	query := pageQuery(url.Values{
+		"productId":    {q.Code},
+		"securityType": {"0101,120100,020100,020200,120200"},
+		"reportType2":  {"DQBG"},
+		"reportType":   {q.ReportType},
+		"beginDate":    {q.BeginDate},
+		"endDate":      {q.EndDate},
+	}, q.PageNo, q.PageSize)
+	p := &BulletinResponse{}
+	if err := s.do(ctx, "/security/stock/queryCompanyBulletin.do", query, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+const pageSize = 25
+
+func (s *Source) GetPeriodicReportAnnouncements(stock *cninfo.Stock, category cninfo.Category, start, end time.Time) ([]*cninfo.Announcement, error) {
+	return s.GetPeriodicReportAnnouncementsContext(context.Background(), stock, category, start, end)
+}
+
+// GetPeriodicReportAnnouncementsContext returns the announcements of
+// category of stock published between start and end, oldest first.
+func (s *Source) GetPeriodicReportAnnouncementsContext(ctx context.Context, stock *cninfo.Stock, category cninfo.Category, start, end time.Time) ([]*cninfo.Announcement, error) {
+	if stock.Market != "" && stock.Market != cninfo.MarketAShare || !Supports(stock.Code) {
+		return nil, fmt.Errorf("sse: stock %s: %w", stock.Code, source.ErrNotSupported)
+	}
+	reportType, ok := reportTypes[category]
+	if !ok {
+		return nil, fmt.Errorf("sse: category %s: %w", category.Name(), source.ErrNotSupported)
+	}
+	if start.After(end) {
+		return nil, errors.New("start time must be before end time")
+	}
+	q := &BulletinQuery{
+		Code:       stock.Code,
+		ReportType: reportType,
+		BeginDate:  start.In(cninfo.Location).Format("2006-01-02"),
+		EndDate:    end.In(cninfo.Location).Format("2006-01-02"),
+		PageSize:   pageSize,
+	}
+	var announcements []*cninfo.Announcement
+	for q.PageNo = 1; ; q.PageNo++ {
+		p, err := s.RequestCompanyBulletinContext(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		for _, bulletin := range p.Result {
+			announcement, err := s.normalize(bulletin)
+			if err != nil {
+				return nil, err
+			}
+			announcements = append(announcements, announcement)
+		}
+		if q.PageNo >= p.PageHelp.PageCount || len(p.Result) == 0 {
+			break
+		}
+	}
+	for i, j := 0, len(announcements)-1; i < j; i, j = i+1, j-1 {
+		announcements[i], announcements[j] = announcements[j], announcements[i]
+	}
+	return announcements, nil
+}
+
+// normalize converts a bulletin. SSE has no announcement id, the file name
+// of the adjunct is unique and used instead.
+func (s *Source) normalize(bulletin *Bulletin) (*cninfo.Announcement, error) {
+	t, err := cninfo.ParseDate(bulletin.SSEDate)
+	if err != nil {
+		return nil, err
+	}
+	name := path.Base(bulletin.URL)
+	ext := path.Ext(name)
+	return &cninfo.Announcement{
+		SecCode:           bulletin.SecurityCode,
+		SecName:           bulletin.SecurityName,
+		TileSecName:       bulletin.SecurityName,
+		AnnouncementID:    strings.TrimSuffix(name, ext),
+		AnnouncementTitle: bulletin.Title,
+		AnnouncementTime:  t.UnixMilli(),
+		AdjunctURL:        s.staticURL() + "/" + strings.TrimPrefix(bulletin.URL, "/"),
+		AdjunctType:       strings.ToUpper(strings.TrimPrefix(ext, ".")),
+	}, nil
+}
+
+func (s *Source) GetDividendRecordsContext(ctx context.Context, stock *cninfo.Stock) ([]*cninfo.DividendRecord, error) {
+	return nil, fmt.Errorf("sse: dividend records: %w", source.ErrNotSupported)
+}
+
+func (s *Source) downloader() *cninfo.Source {
+	return &cninfo.Source{
+		Client:    s.Client,
+		StaticURL: s.staticURL(),
+		UserAgent: s.userAgent(),
+		Timeout:   s.Timeout,
+		Limiter:   s.limiter(),
+		Retry:     s.retry(),
+	}
+}
+
+// DownloadAdjunct streams the adjunct file of announcement to w, resuming
+// and verifying it like cninfo.Source does.
+func (s *Source) DownloadAdjunct(ctx context.Context, announcement *cninfo.Announcement, w io.Writer) error {
+	return s.downloader().DownloadAdjunct(ctx, announcement, w)
+}
+
+func (s *Source) DownloadAdjunctFile(ctx context.Context, announcement *cninfo.Announcement, file string) error {
+	return s.downloader().DownloadAdjunctFile(ctx, announcement, file)
+}
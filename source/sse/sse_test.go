@@ -0,0 +1,113 @@
+package sse_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chamzzzzzz/financial/source"
+	"github.com/chamzzzzzz/financial/source/cninfo"
+	"github.com/chamzzzzzz/financial/source/sse"
+)
+
+func newServer(t *testing.T) *sse.Source {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/security/stock/queryCompanyBulletin.do", func(w http.ResponseWriter, r *http.Request) {
+		if r.Referer() == "" || r.FormValue("productId") != "600000" || r.FormValue("reportType") != "YEARLY" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		switch r.FormValue("pageHelp.pageNo") {
+		case "1":
+			w.Write([]byte(`{"result":[{"SECURITY_CODE":"600000","SECURITY_NAME":"浦发银行","TITLE":"浦发银行2022年年度报告","URL":"/disclosure/listedinfo/announcement/c/new/2023-04-29/600000_20230429_B.pdf","SSEDATE":"2023-04-29"}],"pageHelp":{"pageNo":1,"pageCount":2,"total":2}}`))
+		default:
+			w.Write([]byte(`{"result":[{"SECURITY_CODE":"600000","SECURITY_NAME":"浦发银行","TITLE":"浦发银行2021年年度报告","URL":"/disclosure/listedinfo/announcement/c/new/2022-04-29/600000_20220429_A.pdf","SSEDATE":"2022-04-29"}],"pageHelp":{"pageNo":2,"pageCount":2,"total":2}}`))
+		}
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return &sse.Source{
+		Client:    server.Client(),
+		BaseURL:   server.URL,
+		StaticURL: server.URL,
+		Limiter:   cninfo.NewRateLimiter(0, 1),
+	}
+}
+
+func TestGetPeriodicReportAnnouncements(t *testing.T) {
+	s := newServer(t)
+	start, end := time.Date(2021, 1, 1, 0, 0, 0, 0, cninfo.Location), time.Date(2023, 12, 31, 0, 0, 0, 0, cninfo.Location)
+	announcements, err := s.GetPeriodicReportAnnouncementsContext(context.Background(), &cninfo.Stock{Code: "600000"}, cninfo.CategoryAnnualReport, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(announcements) != 2 {
+		t.Fatalf("got %d announcements, want 2", len(announcements))
+	}
+	a := announcements[0]
+	if a.AnnouncementID != "600000_20220429_A" || a.AdjunctType != "PDF" || a.Time().Format("2006-01-02") != "2022-04-29" {
+		t.Fatalf("got %+v, want the 2021 annual report first", a)
+	}
+
+	_, err = s.GetPeriodicReportAnnouncementsContext(context.Background(), &cninfo.Stock{Code: "000001"}, cninfo.CategoryAnnualReport, start, end)
+	if !errors.Is(err, source.ErrNotSupported) {
+		t.Fatalf("got %v for a shenzhen stock, want ErrNotSupported", err)
+	}
+}
+
+func TestGetStockList(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/sseQuery/commonQuery.do" || r.Referer() == "" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		if requests == 1 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		switch r.FormValue("STOCK_TYPE") {
+		case "1":
+			w.Write([]byte(`{"result":[{"A_STOCK_CODE":"600000","COMPANY_ABBR":"浦发银行 "}],"pageHelp":{"pageNo":1,"pageCount":1,"total":1}}`))
+		case "8":
+			w.Write([]byte(`{"result":[{"A_STOCK_CODE":"688981","COMPANY_ABBR":"中芯国际"}],"pageHelp":{"pageNo":1,"pageCount":1,"total":1}}`))
+		default:
+			w.Write([]byte(`{"result":[],"pageHelp":{"pageNo":1,"pageCount":0,"total":0}}`))
+		}
+	}))
+	defer server.Close()
+	s := &sse.Source{
+		Client:  server.Client(),
+		BaseURL: server.URL,
+		Limiter: cninfo.NewRateLimiter(0, 1),
+		Retry:   &cninfo.RetryPolicy{MaxRetries: 1, MinBackoff: time.Millisecond},
+	}
+	stocks, err := s.GetStockListContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stocks) != 2 || stocks[0].Code != "600000" || stocks[0].Zwjc != "浦发银行" || stocks[1].Code != "688981" || stocks[1].Market != cninfo.MarketAShare {
+		t.Fatalf("got %+v, want 600000 and 688981", stocks)
+	}
+}
+
+func TestSchemaError(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"result":"no data","pageHelp":{}}`))
+	}))
+	defer server.Close()
+	s := &sse.Source{Client: server.Client(), BaseURL: server.URL, Limiter: cninfo.NewRateLimiter(0, 1)}
+	var schemaErr *cninfo.SchemaError
+	if _, err := s.RequestStockListContext(context.Background(), "1", 1); !errors.As(err, &schemaErr) {
+		t.Fatalf("got %v, want SchemaError", err)
+	}
+	if requests != 1 {
+		t.Fatalf("got %d requests, want a schema error not retried", requests)
+	}
+}
@@ -0,0 +1,367 @@
+// Package szse gets stock lists and periodic report announcements directly
+// from the Shenzhen Stock Exchange disclosure API. Results are normalized
+// into the cninfo types and the package registers the "szse" provider.
+package szse
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chamzzzzzz/financial/source"
+	"github.com/chamzzzzzz/financial/source/cninfo"
+)
+
+const (
+	DefaultBaseURL   = "https://www.szse.cn"
+	DefaultStaticURL = "https://disc.static.szse.cn/download"
+)
+
+// DefaultRateLimiter is used by every Source without a Limiter.
+var DefaultRateLimiter = cninfo.NewRateLimiter(200*time.Millisecond, 5)
+
+var categoryIDs = map[cninfo.Category]string{
+	cninfo.CategoryAnnualReport:       "010301",
+	cninfo.CategorySemiAnnualReport:   "010303",
+	cninfo.CategoryFirstQuarterReport: "010305",
+	cninfo.CategoryThirdQuarterReport: "010307",
+}
+
+// Source is a client of the SZSE disclosure API. The zero value is ready to
+// use.
+type Source struct {
+	// Client is used to send requests. If nil, http.DefaultClient is used.
+	Client *http.Client
+
+	// BaseURL is the root of the api endpoints. If empty, DefaultBaseURL is
+	// used.
+	BaseURL string
+
+	// StaticURL is the root of the adjunct files. If empty,
+	// DefaultStaticURL is used.
+	StaticURL string
+
+	// UserAgent is sent with every request. If empty,
+	// cninfo.DefaultUserAgent is used.
+	UserAgent string
+
+	// Timeout limits each request. Zero means no limit.
+	Timeout time.Duration
+
+	// Limiter throttles the requests. If nil, DefaultRateLimiter is used.
+	Limiter *cninfo.RateLimiter
+
+	// Retry decides which failed requests are sent again. If nil,
+	// cninfo.DefaultRetryPolicy is used.
+	Retry *cninfo.RetryPolicy
+}
+
+var _ source.Provider = (*Source)(nil)
+
+func init() {
+	source.Register("szse", func(config *source.Config) (source.Provider, error) {
+		s := &Source{
+			Client:  config.Client,
+			Timeout: config.Timeout,
+		}
+		if config.Interval > 0 {
+			s.Limiter = cninfo.NewRateLimiter(config.Interval, 1)
+		}
+		return s, nil
+	})
+}
+
+func (s *Source) baseURL() string {
+	if s.BaseURL != "" {
+		return strings.TrimSuffix(s.BaseURL, "/")
+	}
+	return DefaultBaseURL
+}
+
+func (s *Source) staticURL() string {
+	if s.StaticURL != "" {
+		return strings.TrimSuffix(s.StaticURL, "/")
+	}
+	return DefaultStaticURL
+}
+
+func (s *Source) userAgent() string {
+	if s.UserAgent != "" {
+		return s.UserAgent
+	}
+	return cninfo.DefaultUserAgent
+}
+
+func (s *Source) limiter() *cninfo.RateLimiter {
+	if s.Limiter != nil {
+		return s.Limiter
+	}
+	return DefaultRateLimiter
+}
+
+func (s *Source) retry() *cninfo.RetryPolicy {
+	if s.Retry != nil {
+		return s.Retry
+	}
+	return cninfo.DefaultRetryPolicy
+}
+
+// Supports reports whether code is listed on SZSE.
+func Supports(code string) bool {
+	return len(code) == 6 && (code[0] == '0' || code[0] == '3')
+}
+
+func (s *Source) do(ctx context.Context, method, url string, body any, v any) error {
+	return s.retry().Do(ctx, func() error {
+		return s.send(ctx, method, url, body, v)
+	})
+}
+
+// send sends one attempt of a request of do.
+func (s *Source) send(ctx context.Context, method, url string, body any, v any) error {
+	if err := s.limiter().Wait(ctx); err != nil {
+		return err
+	}
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json, text/javascript, */*")
+	req.Header.Set("User-Agent", s.userAgent())
+	req.Header.Set("Referer", s.baseURL()+"/disclosure/listed/notice/index.html")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("szse: %w", &cninfo.StatusError{StatusCode: resp.StatusCode})
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("szse: %w", &cninfo.SchemaError{URL: req.URL.String(), Err: err})
+	}
+	return nil
+}
+
+type StockListResponse []struct {
+	Metadata struct {
+		PageNo      int `json:"pageno"`
+		PageCount   int `json:"pagecount"`
+		RecordCount int `json:"recordcount"`
+	} `json:"metadata"`
+	Data []struct {
+		Code string `json:"agdm"`
+		Name string `json:"agjc"`
+	} `json:"data"`
+}
+
+func (s *Source) RequestStockList(pageNo int) (*StockListResponse, error) {
+	return s.RequestStockListContext(context.Background(), pageNo)
+}
+
+func (s *Source) RequestStockListContext(ctx context.Context, pageNo int) (*StockListResponse, error) {
+	url := fmt.Sprintf("%s/api/report/ShowReport/data?SHOWTYPE=JSON&CATALOGID=1110&TABKEY=tab1&PAGENO=%d", s.baseURL(), pageNo)
+	p := &StockListResponse{}
+	if err := s.do(ctx, "GET", url, nil, p); err != nil {
+		return nil, err
+	}
+	if len(*p) == 0 {
+		return nil, errors.New("szse: empty stock list response")
+	}
+	return p, nil
+}
+
+var tagRegexp = regexp.MustCompile(`<[^>]*>`)
+
+func (s *Source) GetStockList() ([]*cninfo.Stock, error) {
+	return s.GetStockListContext(context.Background())
+}
+
+func (s *Source) GetStockListContext(ctx context.Context) ([]*cninfo.Stock, error) {
+	var stocks []*cninfo.Stock
+	for pageNo := 1; ; pageNo++ {
+		p, err := s.RequestStockListContext(ctx, pageNo)
+		if err != nil {
+			return nil, err
+		}
+		tab := (*p)[0]
+		for _, data := range tab.Data {
+			stocks = append(stocks, &cninfo.Stock{
+				Code:     data.Code,
+				Category: "A股",
+				Zwjc:     strings.TrimSpace(tagRegexp.ReplaceAllString(data.Name, "")),
+				Market:   cninfo.MarketAShare,
+			})
+		}
+		if pageNo >= tab.Metadata.PageCount || len(tab.Data) == 0 {
+			break
+		}
+	}
+	return stocks, nil
+}
+
+type AnnouncementListRequest struct {
+	SeDate        []string `json:"seDate"`
+	Stock         []string `json:"stock"`
+	ChannelCode   []string `json:"channelCode"`
+	BigCategoryID []string `json:"bigCategoryId"`
+	PageSize      int      `json:"pageSize"`
+	PageNum       int      `json:"pageNum"`
+}
+
+type Announcement struct {
+	ID           string   `json:"id"`
+	AnnID        int64    `json:"annId"`
+	Title        string   `json:"title"`
+	PublishTime  string   `json:"publishTime"`
+	AttachPath   string   `json:"attachPath"`
+	AttachFormat string   `json:"attachFormat"`
+	AttachSize   int      `json:"attachSize"`
+	SecCode      []string `json:"secCode"`
+	SecName      []string `json:"secName"`
+}
+
+type AnnouncementListResponse struct {
+	AnnounceCount int             `json:"announceCount"`
+	Data          []*Announcement `json:"data"`
+}
+
+func (s *Source) RequestAnnouncementList(q *AnnouncementListRequest) (*AnnouncementListResponse, error) {
+	return s.RequestAnnouncementListContext(context.Background(), q)
+}
+
+func (s *Source) RequestAnnouncementListContext(ctx context.Context, q *AnnouncementListRequest) (*AnnouncementListResponse, error) {
+	p := &AnnouncementListResponse{}
+	if err := s.do(ctx, "POST", s.baseURL()+"/api/disc/announcement/annList", q, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+const pageSize = 50
+
+func (s *Source) GetPeriodicReportAnnouncements(stock *cninfo.Stock, category cninfo.Category, start, end time.Time) ([]*cninfo.Announcement, error) {
+	return s.GetPeriodicReportAnnouncementsContext(context.Background(), stock, category, start, end)
+}
+
+// GetPeriodicReportAnnouncementsContext returns the announcements of
+// category of stock published between start and end, oldest first.
+func (s *Source) GetPeriodicReportAnnouncementsContext(ctx context.Context, stock *cninfo.Stock, category cninfo.Category, start, end time.Time) ([]*cninfo.Announcement, error) {
+	if stock.Market != "" && stock.Market != cninfo.MarketAShare || !Supports(stock.Code) {
+		return nil, fmt.Errorf("szse: stock %s: %w", stock.Code, source.ErrNotSupported)
+	}
+	id, ok := categoryIDs[category]
+	if !ok {
+		return nil, fmt.Errorf("szse: category %s: %w", category.Name(), source.ErrNotSupported)
+	}
+	if start.After(end) {
+		return nil, errors.New("start time must be before end time")
+	}
+	q := &AnnouncementListRequest{
+		SeDate:        []string{start.In(cninfo.Location).Format("2006-01-02"), end.In(cninfo.Location).Format("2006-01-02")},
+		Stock:         []string{stock.Code},
+		ChannelCode:   []string{"listedNotice_disc"},
+		BigCategoryID: []string{id},
+		PageSize:      pageSize,
+	}
+	var announcements []*cninfo.Announcement
+	for q.PageNum = 1; ; q.PageNum++ {
+		p, err := s.RequestAnnouncementListContext(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		for _, data := range p.Data {
+			announcement, err := s.normalize(data)
+			if err != nil {
+				return nil, err
+			}
+			announcements = append(announcements, announcement)
+		}
+		if len(p.Data) < pageSize || q.PageNum*pageSize >= p.AnnounceCount {
+			break
+		}
+	}
+	for i, j := 0, len(announcements)-1; i < j; i, j = i+1, j-1 {
+		announcements[i], announcements[j] = announcements[j], announcements[i]
+	}
+	return announcements, nil
+}
+
+func (s *Source) normalize(data *Announcement) (*cninfo.Announcement, error) {
+	t, err := cninfo.ParseDate(data.PublishTime)
+	if err != nil {
+		return nil, err
+	}
+	a := &cninfo.Announcement{
+		AnnouncementID:    data.ID,
+		AnnouncementTitle: data.Title,
+		AnnouncementTime:  t.UnixMilli(),
+		AdjunctURL:        s.staticURL() + "/" + strings.TrimPrefix(data.AttachPath, "/"),
+		AdjunctSize:       data.AttachSize,
+		AdjunctType:       strings.ToUpper(data.AttachFormat),
+	}
+	if a.AnnouncementID == "" {
+		a.AnnouncementID = strconv.FormatInt(data.AnnID, 10)
+	}
+	if len(data.SecCode) > 0 {
+		a.SecCode = data.SecCode[0]
+	}
+	if len(data.SecName) > 0 {
+		a.SecName = data.SecName[0]
+		a.TileSecName = data.SecName[0]
+	}
+	return a, nil
+}
+
+func (s *Source) GetDividendRecordsContext(ctx context.Context, stock *cninfo.Stock) ([]*cninfo.DividendRecord, error) {
+	return nil, fmt.Errorf("szse: dividend records: %w", source.ErrNotSupported)
+}
+
+func (s *Source) downloader() *cninfo.Source {
+	return &cninfo.Source{
+		Client:    s.Client,
+		StaticURL: s.staticURL(),
+		UserAgent: s.userAgent(),
+		Timeout:   s.Timeout,
+		Limiter:   s.limiter(),
+		Retry:     s.retry(),
+	}
+}
+
+// DownloadAdjunct streams the adjunct file of announcement to w, resuming
+// and verifying it like cninfo.Source does.
+func (s *Source) DownloadAdjunct(ctx context.Context, announcement *cninfo.Announcement, w io.Writer) error {
+	return s.downloader().DownloadAdjunct(ctx, announcement, w)
+}
+
+func (s *Source) DownloadAdjunctFile(ctx context.Context, announcement *cninfo.Announcement, file string) error {
+	return s.downloader().DownloadAdjunctFile(ctx, announcement, file)
+}
@@ -0,0 +1,121 @@
+package szse_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chamzzzzzz/financial/source"
+	"github.com/chamzzzzzz/financial/source/cninfo"
+	"github.com/chamzzzzzz/financial/source/szse"
+)
+
+func newServer(t *testing.T) *szse.Source {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/report/ShowReport/data", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"metadata":{"pageno":1,"pagecount":1,"recordcount":2},"data":[
+			{"agdm":"000001","agjc":"<a href='#'><u>平安银行</u></a>"},
+			{"agdm":"300750","agjc":"<a href='#'><u>宁德时代</u></a>"}]}]`))
+	})
+	mux.HandleFunc("/api/disc/announcement/annList", func(w http.ResponseWriter, r *http.Request) {
+		var q szse.AnnouncementListRequest
+		if err := json.NewDecoder(r.Body).Decode(&q); err != nil || q.BigCategoryID[0] != "010301" || q.Stock[0] != "000001" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"announceCount":2,"data":[
+			{"id":"b","title":"平安银行：2022年年度报告","publishTime":"2023-03-09 00:00:00","attachPath":"/disc/disk03/finalpage/2023-03-09/b.PDF","attachFormat":"pdf","attachSize":100,"secCode":["000001"],"secName":["平安银行"]},
+			{"id":"a","title":"平安银行：2021年年度报告","publishTime":"2022-03-10 00:00:00","attachPath":"/disc/disk03/finalpage/2022-03-10/a.PDF","attachFormat":"pdf","attachSize":100,"secCode":["000001"],"secName":["平安银行"]}]}`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return &szse.Source{
+		Client:    server.Client(),
+		BaseURL:   server.URL,
+		StaticURL: server.URL + "/download",
+		Limiter:   cninfo.NewRateLimiter(0, 1),
+	}
+}
+
+func TestGetStockList(t *testing.T) {
+	stocks, err := newServer(t).GetStockListContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stocks) != 2 || stocks[0].Code != "000001" || stocks[0].Zwjc != "平安银行" || stocks[1].Market != cninfo.MarketAShare {
+		t.Fatalf("got %+v", stocks)
+	}
+}
+
+func TestGetPeriodicReportAnnouncements(t *testing.T) {
+	s := newServer(t)
+	start, end := time.Date(2021, 1, 1, 0, 0, 0, 0, cninfo.Location), time.Date(2023, 12, 31, 0, 0, 0, 0, cninfo.Location)
+	announcements, err := s.GetPeriodicReportAnnouncementsContext(context.Background(), &cninfo.Stock{Code: "000001"}, cninfo.CategoryAnnualReport, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(announcements) != 2 {
+		t.Fatalf("got %d announcements, want 2", len(announcements))
+	}
+	a := announcements[0]
+	if a.AnnouncementID != "a" || a.SecCode != "000001" || a.AdjunctType != "PDF" || a.Time().Format("2006-01-02") != "2022-03-10" {
+		t.Fatalf("got %+v, want the 2021 annual report first", a)
+	}
+	if want := s.StaticURL + "/disc/disk03/finalpage/2022-03-10/a.PDF"; a.AdjunctURL != want {
+		t.Fatalf("got adjunct url %s, want %s", a.AdjunctURL, want)
+	}
+
+	_, err = s.GetPeriodicReportAnnouncementsContext(context.Background(), &cninfo.Stock{Code: "600000"}, cninfo.CategoryAnnualReport, start, end)
+	if !errors.Is(err, source.ErrNotSupported) {
+		t.Fatalf("got %v for a shanghai stock, want ErrNotSupported", err)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= 2 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`[{"metadata":{"pageno":1,"pagecount":1,"recordcount":1},"data":[{"agdm":"000001","agjc":"平安银行"}]}]`))
+	}))
+	defer server.Close()
+	s := &szse.Source{
+		Client:  server.Client(),
+		BaseURL: server.URL,
+		Limiter: cninfo.NewRateLimiter(0, 1),
+		Retry:   &cninfo.RetryPolicy{MaxRetries: 2, MinBackoff: time.Millisecond},
+	}
+	stocks, err := s.GetStockListContext(context.Background())
+	if err != nil || len(stocks) != 1 || stocks[0].Code != "000001" {
+		t.Fatalf("got %+v, %v, want 000001 after two retries", stocks, err)
+	}
+
+	requests = 0
+	s.Retry = cninfo.NoRetry
+	var statusErr *cninfo.StatusError
+	if _, err := s.GetStockListContext(context.Background()); !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("got %v, want the 503 without retry", err)
+	}
+	if requests != 1 {
+		t.Fatalf("got %d requests, want 1", requests)
+	}
+}
+
+func TestSchemaError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"announceCount":"many","data":[]}`))
+	}))
+	defer server.Close()
+	s := &szse.Source{Client: server.Client(), BaseURL: server.URL, Limiter: cninfo.NewRateLimiter(0, 1)}
+	var schemaErr *cninfo.SchemaError
+	if _, err := s.RequestAnnouncementListContext(context.Background(), &szse.AnnouncementListRequest{}); !errors.As(err, &schemaErr) {
+		t.Fatalf("got %v, want SchemaError", err)
+	}
+}